@@ -58,7 +58,7 @@ func processRequest(ctx context.Context) {
 func validateInput(ctx context.Context) {
 	logger.AddContextValue(ctx, "validation_step", "input_check")
 	logger.Debugf(ctx, "Checking input format")
-	logger.Infof(ctx, "Input validation passed")
+	logger.Infow(ctx, "Input validation passed", "fields_checked", 5, "strict", true)
 }
 
 func processData(ctx context.Context) {
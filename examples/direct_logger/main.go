@@ -32,6 +32,11 @@ func demonstrateBasicUsage() {
 	logger.D.Errorf("Error: Failed to connect to database: %v", "connection timeout")
 	logger.D.Criticalf("Critical: System is running out of memory")
 
+	// Structured per-line fields as alternating key/value pairs
+	structured := logger.Structured(logger.D)
+	structured.Infow("Order placed", "order_id", "ord-123", "amount", 4200)
+	structured.Errorw("Upstream request failed", "status", 502, "retryable", true)
+
 	fmt.Println("\nThe global logger.D instance uses the configuration from logger.Init()")
 }
 
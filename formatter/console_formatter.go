@@ -0,0 +1,219 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ANSI escape sequences used by ConsoleFormatter
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBoldRed = "\x1b[1;31m"
+	ansiGray    = "\x1b[90m"
+)
+
+// DefaultConsoleTimeFormat is the default time format of entry lines
+const DefaultConsoleTimeFormat = "15:04:05.000"
+
+// DefaultConsoleContextFields are the context fields shown in the header when
+// ConsoleFormatter.ContextFields is empty
+var DefaultConsoleContextFields = []string{"method", "path", "status_code", "request_id"}
+
+// ConsoleFormatter implements the Formatter interface for human-readable terminal output
+//
+// Each aggregated log is printed as a header line with the type, severity, elapsed time
+// and key context fields, followed by one indented line per entry with the timestamp, level,
+// message, fields and source location:
+//
+//	[request] WARN 45ms method=GET path=/api/users status_code=200
+//	  10:00:00.123 INFO  Request started
+//	  10:00:00.150 WARN  slow query table=users (db.go:42)
+type ConsoleFormatter struct {
+	// Color enables ANSI colors
+	Color bool
+
+	// ContextFields lists the context fields shown in the header, in order
+	// Empty means DefaultConsoleContextFields
+	ContextFields []string
+
+	// AllContextFields shows every context field in the header, sorted by key, instead of
+	// ContextFields
+	AllContextFields bool
+
+	// TimeFormat is the time format of entry lines (default DefaultConsoleTimeFormat)
+	TimeFormat string
+}
+
+// NewConsoleFormatter creates a ConsoleFormatter for standard output
+// Colors are enabled when standard output is a terminal and NO_COLOR is not set
+func NewConsoleFormatter() *ConsoleFormatter {
+	return NewConsoleFormatterFor(os.Stdout)
+}
+
+// NewConsoleFormatterFor creates a ConsoleFormatter for the given output
+// Colors are enabled when the output is a terminal and NO_COLOR is not set
+func NewConsoleFormatterFor(w io.Writer) *ConsoleFormatter {
+	return &ConsoleFormatter{
+		Color:      ColorEnabled(w),
+		TimeFormat: DefaultConsoleTimeFormat,
+	}
+}
+
+// ColorEnabled reports whether colored output should be written to w
+// It returns false when the NO_COLOR environment variable is set to a non-empty value
+// (see https://no-color.org) or when w is not a terminal.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Format formats the log output as human-readable text
+func (f *ConsoleFormatter) Format(output *LogOutput) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// Header line
+	buf.WriteString(f.paint(ansiBold, "["+output.Type+"]"))
+	buf.WriteByte(' ')
+	buf.WriteString(f.paint(levelColor(output.Runtime.Severity), output.Runtime.Severity))
+	fmt.Fprintf(&buf, " %dms", output.Runtime.Elapsed)
+	if output.Runtime.Truncated {
+		buf.WriteByte(' ')
+		buf.WriteString(f.paint(ansiYellow, fmt.Sprintf("(%d lines dropped)", output.Runtime.DroppedLines)))
+	}
+	for _, key := range f.headerKeys(output.Context) {
+		value, ok := output.Context[key]
+		if !ok {
+			continue
+		}
+		buf.WriteByte(' ')
+		f.writeField(&buf, key, value)
+	}
+
+	// Entry lines
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = DefaultConsoleTimeFormat
+	}
+	for _, entry := range output.Runtime.Lines {
+		buf.WriteString("\n  ")
+		buf.WriteString(f.paint(ansiGray, entry.Timestamp.Format(timeFormat)))
+		buf.WriteByte(' ')
+		buf.WriteString(f.paint(levelColor(entry.Level), fmt.Sprintf("%-5s", entry.Level)))
+		buf.WriteByte(' ')
+		buf.WriteString(entry.Message)
+		if entry.Truncated {
+			buf.WriteString(f.paint(ansiGray, "…"))
+		}
+
+		for _, key := range sortedKeys(entry.Fields) {
+			buf.WriteByte(' ')
+			f.writeField(&buf, key, entry.Fields[key])
+		}
+		if entry.Filename != "" {
+			location := fmt.Sprintf("(%s:%d)", entry.Filename, entry.Fileline)
+			buf.WriteByte(' ')
+			buf.WriteString(f.paint(ansiGray, location))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// headerKeys returns the context fields shown in the header
+func (f *ConsoleFormatter) headerKeys(context map[string]interface{}) []string {
+	if f.AllContextFields {
+		return sortedKeys(context)
+	}
+	if len(f.ContextFields) > 0 {
+		return f.ContextFields
+	}
+	return DefaultConsoleContextFields
+}
+
+// writeField writes key=value with a dimmed key
+func (f *ConsoleFormatter) writeField(buf *bytes.Buffer, key string, value interface{}) {
+	buf.WriteString(f.paint(ansiDim, key+"="))
+	buf.WriteString(consoleValue(value))
+}
+
+// paint wraps s in the ANSI color if colors are enabled
+func (f *ConsoleFormatter) paint(color, s string) string {
+	if !f.Color || color == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// levelColor returns the ANSI color of a level
+func levelColor(level string) string {
+	switch level {
+	case "DEBUG":
+		return ansiGray
+	case "INFO":
+		return ansiGreen
+	case "WARN":
+		return ansiYellow
+	case "ERROR":
+		return ansiRed
+	case "CRITICAL":
+		return ansiBoldRed
+	default:
+		return ""
+	}
+}
+
+// consoleValue formats a field value for the console
+// Strings are quoted when they contain spaces or quotes; maps and slices are written as JSON
+func consoleValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	case nil:
+		return "null"
+	case fmt.Stringer:
+		return consoleValue(v.String())
+	case error:
+		return consoleValue(v.Error())
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return consoleValue(fmt.Sprint(v))
+		}
+		return string(data)
+	}
+}
+
+// sortedKeys returns the keys of the map in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package formatter
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleFormatter_Plain(t *testing.T) {
+	formatter := &ConsoleFormatter{}
+
+	result, err := formatter.Format(testLogOutput())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		`[request] WARN 45ms method=GET path=/api/users status_code=200`,
+		`  10:00:00.123 INFO  Request started`,
+		`  10:00:00.150 WARN  slow query query="SELECT 1" rows=3 table=users (db.go:42)`,
+	}, "\n")
+	if string(result) != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", result, expected)
+	}
+}
+
+func TestConsoleFormatter_ContextFieldsAndTimeFormat(t *testing.T) {
+	formatter := &ConsoleFormatter{
+		ContextFields: []string{"path", "missing"},
+		TimeFormat:    time.RFC3339,
+	}
+
+	result, _ := formatter.Format(testLogOutput())
+	header := strings.SplitN(string(result), "\n", 2)[0]
+	if header != "[request] WARN 45ms path=/api/users" {
+		t.Errorf("Expected only the selected context fields, got %q", header)
+	}
+	if !strings.Contains(string(result), "2024-01-02T10:00:00Z INFO") {
+		t.Errorf("Expected the custom time format, got %s", result)
+	}
+}
+
+func TestConsoleFormatter_AllContextFields(t *testing.T) {
+	formatter := &ConsoleFormatter{AllContextFields: true}
+
+	result, _ := formatter.Format(testLogOutput())
+	header := strings.SplitN(string(result), "\n", 2)[0]
+	expected := `[request] WARN 45ms headers={"Accept":"*/*","X-Request-Id":"abc"} method=GET path=/api/users ` +
+		`status_code=200 tags=["a","b"] tenant=acme trace_id=4bf92f3577b34da6a3ce929d0e0e4736`
+	if header != expected {
+		t.Errorf("Expected every context field, got %q", header)
+	}
+}
+
+func TestConsoleFormatter_Color(t *testing.T) {
+	formatter := &ConsoleFormatter{Color: true}
+
+	result, _ := formatter.Format(testLogOutput())
+	if !strings.Contains(string(result), ansiYellow+"WARN"+ansiReset) {
+		t.Errorf("Expected a yellow WARN level, got %q", result)
+	}
+	if !strings.Contains(string(result), ansiGreen+"INFO "+ansiReset) {
+		t.Errorf("Expected a green INFO level, got %q", result)
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	if ColorEnabled(&bytes.Buffer{}) {
+		t.Error("Expected no color for a non-file writer")
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "console")
+	if err != nil {
+		t.Fatalf("CreateTemp failed: %v", err)
+	}
+	defer file.Close()
+	if ColorEnabled(file) {
+		t.Error("Expected no color for a regular file")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(os.Stdout) {
+		t.Error("Expected NO_COLOR to disable colors")
+	}
+	if NewConsoleFormatter().Color {
+		t.Error("Expected NewConsoleFormatter to honour NO_COLOR")
+	}
+}
+
+func TestConsoleFormatter_TruncationMarkers(t *testing.T) {
+	output := testLogOutput()
+	output.Runtime.Truncated = true
+	output.Runtime.DroppedLines = 812
+	output.Runtime.Lines[0].Truncated = true
+
+	result, err := (&ConsoleFormatter{}).Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.HasPrefix(string(result), "[request] WARN 45ms (812 lines dropped) method=GET") {
+		t.Errorf("Expected the dropped line count in the header, got %s", result)
+	}
+	if !strings.Contains(string(result), "Request started…\n") {
+		t.Errorf("Expected the truncated message to be marked, got %s", result)
+	}
+}
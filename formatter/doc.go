@@ -8,6 +8,12 @@
 //
 //   - JSONFormatter: Standard JSON output with optional indentation
 //   - ContextFlattenFormatter: Flattens context fields to the top level
+//   - ConsoleFormatter: Human-readable, optionally colored text for terminals
+//   - LogfmtFormatter: logfmt, one line per aggregated log or per entry
+//   - ExplodedJSONFormatter: One JSON document per entry with a shared aggregate ID
+//   - ECSFormatter: Elastic Common Schema documents with configurable field mapping
+//   - GCPFormatter: Google Cloud Logging structured logs with severity, httpRequest and trace
+//   - EMFFormatter: AWS CloudWatch Embedded Metric Format with elapsed and error metrics
 //
 // # Basic Usage
 //
@@ -32,8 +38,10 @@
 //
 //   - LogEntry: Represents a single log entry with timestamp, level, and message
 //   - LogOutput: Complete log output structure with context and runtime information
-//   - RuntimeInfo: Runtime information including severity, timing, and log entries
+//   - RuntimeInfo: Runtime information including severity, timing, log entries and spans
+//   - SpanInfo: A timed sub-operation with its own severity, fields and nested spans
 //   - Formatter: Interface for implementing custom formatters
+//   - RecordFormatter: Formatter that splits a log output into several records
 //
 // # JSON Format
 //
@@ -81,6 +89,70 @@
 //	  }
 //	}
 //
+// # Console Format
+//
+// The console format prints a header line per aggregated log and one indented line per entry:
+//
+//	[request] WARN 45ms method=GET path=/api/users
+//	  10:00:00.123 INFO  Request started
+//	  10:00:00.150 WARN  slow query rows=3 table=users (db.go:42)
+//
+// The header shows the key context fields of DefaultConsoleContextFields; set ContextFields
+// to choose others or AllContextFields to show every context field.
+//
+// NewConsoleFormatterFor enables colors when the output is a terminal and the NO_COLOR
+// environment variable is not set:
+//
+//	contextLogger.SetFormatter(formatter.NewConsoleFormatterFor(os.Stderr))
+//
+// # Logfmt Format
+//
+// NewLogfmtFormatter writes one logfmt line per aggregated log with the context fields and
+// a summary of the runtime information:
+//
+//	type=request severity=WARN start_time=... end_time=... elapsed=45 lines=2 msg="slow query" method=GET
+//
+// NewExplodedLogfmtFormatter writes one line per entry with the context fields repeated and
+// a shared aggregate_id:
+//
+//	time=... level=INFO msg="Request started" type=request aggregate_id=9f86d081884c7d65 seq=1 method=GET
+//	time=... level=WARN msg="slow query" type=request aggregate_id=9f86d081884c7d65 seq=2 method=GET rows=3
+//
+// # Exploded JSON Format
+//
+// ExplodedJSONFormatter writes each entry as its own JSON document with the shared context,
+// an aggregateId shared by the documents of the same log, and its position:
+//
+//	{"type":"request","context":{"request_id":"req-123"},"aggregateId":"9f86d081884c7d65","seq":1,"total":1,"severity":"INFO","timestamp":"...","level":"INFO","message":"Processing started"}
+//
+// It implements RecordFormatter; loggers write each record returned by FormatRecords as a
+// separate document.
+//
+// # ECS Format
+//
+// ECSFormatter maps the log output onto Elastic Common Schema fields (@timestamp, log.level,
+// message, event.duration, log.origin, http.request.method, url.path, ...). Context keys are
+// mapped with DefaultECSFieldMap and can be remapped for custom keys:
+//
+//	formatter.NewECSFormatterWithFieldMap(map[string]string{"tenant": "organization.name"})
+//
+// # Google Cloud Logging Format
+//
+// GCPFormatter adds the keys Cloud Logging recognises (severity, timestamp, httpRequest,
+// logging.googleapis.com/trace, logging.googleapis.com/sourceLocation, ...) to the standard
+// document. The trace resource name is built from the project ID:
+//
+//	formatter.NewGCPFormatter("my-project")
+//
+// # CloudWatch Embedded Metric Format
+//
+// EMFFormatter adds an _aws metadata block and the Elapsed, LineCount, ErrorCount and Fault
+// metrics to the standard document, with dimensions taken from context keys:
+//
+//	formatter.NewEMFFormatter("MyService", "method", "path")
+//
+// Dimensions named like a document field or a metric are written as dim_<name>.
+//
 // # Custom Formatters
 //
 // You can implement custom formatters by implementing the Formatter interface:
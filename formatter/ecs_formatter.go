@@ -0,0 +1,222 @@
+package formatter
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ECSVersion is the Elastic Common Schema version written in ecs.version
+const ECSVersion = "8.11.0"
+
+// Default keys of the logspan-specific fields written by ECSFormatter
+const (
+	DefaultECSLinesKey   = "logspan.lines"
+	DefaultECSContextKey = "logspan.context"
+	DefaultECSTypeKey    = "logspan.type"
+	DefaultECSSpansKey   = "logspan.spans"
+)
+
+// DefaultECSFieldMap maps the context fields written by the HTTP middleware and the trace
+// helpers onto ECS fields
+var DefaultECSFieldMap = map[string]string{
+	"method":                "http.request.method",
+	"url":                   "url.original",
+	"path":                  "url.path",
+	"query":                 "url.query",
+	"host":                  "url.domain",
+	"user_agent":            "user_agent.original",
+	"remote_addr":           "client.address",
+	"content_type":          "http.request.mime_type",
+	"request_bytes":         "http.request.body.bytes",
+	"status_code":           "http.response.status_code",
+	"response_bytes":        "http.response.body.bytes",
+	"response_content_type": "http.response.mime_type",
+	"trace_id":              "trace.id",
+	"span_id":               "span.id",
+}
+
+// ECSFormatter implements the Formatter interface for Elastic Common Schema (ECS) output
+//
+// The aggregated log is written as one ECS document:
+//
+//	{
+//	  "@timestamp": "2024-01-02T10:00:00.1Z",
+//	  "ecs": {"version": "8.11.0"},
+//	  "event": {"start": "...", "end": "...", "duration": 45000000},
+//	  "log": {"level": "warn", "origin": {"function": "main.query", "file": {"name": "db.go", "line": 42}}},
+//	  "message": "slow query",
+//	  "http": {"request": {"method": "GET"}, "response": {"status_code": 200}},
+//	  "url": {"path": "/api/users"},
+//	  "logspan": {"type": "request", "lines": [...]}
+//	}
+//
+// @timestamp and event.start are the start time of the log, event.duration is the elapsed
+// time in nanoseconds, and log.level is the aggregated severity in lower case. message and
+// log.origin come from the first line with the highest severity.
+//
+// Context fields are mapped onto ECS fields with DefaultECSFieldMap and FieldMap; the other
+// context fields are written under ContextKey. Entries are written under LinesKey as ECS
+// objects with their own @timestamp, log.level, message, log.origin and span.id.
+type ECSFormatter struct {
+	// FieldMap maps context keys to dotted ECS field names, in addition to DefaultECSFieldMap
+	// Mapping a key to an empty string writes it under ContextKey instead
+	FieldMap map[string]string
+
+	// LinesKey is the dotted field name of the entries (default DefaultECSLinesKey)
+	LinesKey string
+
+	// ContextKey is the dotted field name of unmapped context fields (default DefaultECSContextKey)
+	ContextKey string
+
+	// Indent specifies the indentation string for pretty printing
+	// Empty string means no indentation (compact JSON)
+	Indent string
+}
+
+// NewECSFormatter creates a new ECSFormatter with the default field mapping
+func NewECSFormatter() *ECSFormatter {
+	return &ECSFormatter{
+		LinesKey:   DefaultECSLinesKey,
+		ContextKey: DefaultECSContextKey,
+	}
+}
+
+// NewECSFormatterWithFieldMap creates a new ECSFormatter with additional context field mappings
+// The mappings override DefaultECSFieldMap for the same keys.
+func NewECSFormatterWithFieldMap(fieldMap map[string]string) *ECSFormatter {
+	f := NewECSFormatter()
+	f.FieldMap = fieldMap
+	return f
+}
+
+// Format formats the log output as an ECS JSON document
+func (f *ECSFormatter) Format(output *LogOutput) ([]byte, error) {
+	doc := make(ecsObject)
+
+	setPath(doc, "@timestamp", output.Runtime.StartTime)
+	setPath(doc, "ecs.version", ECSVersion)
+	setPath(doc, "log.level", strings.ToLower(output.Runtime.Severity))
+	setPath(doc, "event.start", output.Runtime.StartTime)
+	setPath(doc, "event.end", output.Runtime.EndTime)
+	setPath(doc, "event.duration", eventDuration(output.Runtime))
+	setPath(doc, DefaultECSTypeKey, output.Type)
+	if output.Runtime.Truncated {
+		setPath(doc, "logspan.truncated", true)
+		setPath(doc, "logspan.dropped_lines", output.Runtime.DroppedLines)
+	}
+
+	if entry := mostSevereEntry(output.Runtime.Lines); entry != nil {
+		setPath(doc, "message", entry.Message)
+		setOrigin(doc, entry)
+	}
+
+	// Context fields, in sorted order so that conflicting mappings resolve deterministically
+	contextKey := f.ContextKey
+	if contextKey == "" {
+		contextKey = DefaultECSContextKey
+	}
+	for _, key := range sortedKeys(output.Context) {
+		if field := f.mappedField(key); field != "" {
+			setPath(doc, field, output.Context[key])
+		} else {
+			setPath(doc, contextKey+"."+key, output.Context[key])
+		}
+	}
+
+	linesKey := f.LinesKey
+	if linesKey == "" {
+		linesKey = DefaultECSLinesKey
+	}
+	lines := make([]ecsObject, len(output.Runtime.Lines))
+	for i, entry := range output.Runtime.Lines {
+		lines[i] = ecsLine(entry)
+	}
+	setPath(doc, linesKey, lines)
+
+	if len(output.Runtime.Spans) > 0 {
+		setPath(doc, DefaultECSSpansKey, output.Runtime.Spans)
+	}
+
+	if f.Indent == "" {
+		return json.Marshal(doc)
+	}
+	return json.MarshalIndent(doc, "", f.Indent)
+}
+
+// mappedField returns the ECS field of a context key, or "" if it is not mapped
+func (f *ECSFormatter) mappedField(key string) string {
+	if field, ok := f.FieldMap[key]; ok {
+		return field
+	}
+	return DefaultECSFieldMap[key]
+}
+
+// ecsLine converts an entry into an ECS object
+func ecsLine(entry *LogEntry) ecsObject {
+	line := make(ecsObject)
+	setPath(line, "@timestamp", entry.Timestamp.Format(time.RFC3339Nano))
+	setPath(line, "log.level", strings.ToLower(entry.Level))
+	setPath(line, "message", entry.Message)
+	setOrigin(line, entry)
+	if entry.SpanID != "" {
+		setPath(line, "span.id", entry.SpanID)
+	}
+	if len(entry.Fields) > 0 {
+		setPath(line, "fields", entry.Fields)
+	}
+	if entry.Truncated {
+		setPath(line, "logspan.truncated", true)
+	}
+	return line
+}
+
+// setOrigin writes the source information of the entry to log.origin
+func setOrigin(doc ecsObject, entry *LogEntry) {
+	if entry.Funcname != "" {
+		setPath(doc, "log.origin.function", entry.Funcname)
+	}
+	if entry.Filename != "" {
+		setPath(doc, "log.origin.file.name", entry.Filename)
+		setPath(doc, "log.origin.file.line", entry.Fileline)
+	}
+}
+
+// eventDuration returns the duration of the log in nanoseconds
+// The start and end times are used when they can be parsed, the elapsed milliseconds otherwise
+func eventDuration(runtime RuntimeInfo) int64 {
+	start, startErr := time.Parse(time.RFC3339Nano, runtime.StartTime)
+	end, endErr := time.Parse(time.RFC3339Nano, runtime.EndTime)
+	if startErr != nil || endErr != nil {
+		return runtime.Elapsed * int64(time.Millisecond)
+	}
+	return end.Sub(start).Nanoseconds()
+}
+
+// ecsObject is a JSON object created by setPath
+// Values taken from the log output are never modified, even when they are maps.
+type ecsObject map[string]interface{}
+
+// setPath stores value in doc under a dotted field name, creating nested objects as needed
+// If a path segment is already used by another value, the remaining path is used as a
+// dotted key at that level.
+func setPath(doc ecsObject, path string, value interface{}) {
+	parts := strings.Split(path, ".")
+	current := doc
+	for i, part := range parts[:len(parts)-1] {
+		next, exists := current[part]
+		if !exists {
+			child := make(ecsObject)
+			current[part] = child
+			current = child
+			continue
+		}
+		child, ok := next.(ecsObject)
+		if !ok {
+			current[strings.Join(parts[i:], ".")] = value
+			return
+		}
+		current = child
+	}
+	current[parts[len(parts)-1]] = value
+}
@@ -0,0 +1,135 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestECSFormatter_Format(t *testing.T) {
+	doc := decodeDocument(t, NewECSFormatter(), testLogOutput())
+
+	expected := map[string]interface{}{
+		"ecs.version":               ECSVersion,
+		"log.level":                 "warn",
+		"message":                   "slow query",
+		"event.start":               "2024-01-02T10:00:00.1Z",
+		"event.end":                 "2024-01-02T10:00:00.1451234Z",
+		"event.duration":            float64(45123400),
+		"log.origin.function":       "main.query",
+		"log.origin.file.name":      "db.go",
+		"log.origin.file.line":      float64(42),
+		"http.request.method":       "GET",
+		"url.path":                  "/api/users",
+		"http.response.status_code": float64(200),
+		"trace.id":                  "4bf92f3577b34da6a3ce929d0e0e4736",
+		"logspan.type":              "request",
+		"logspan.context.tenant":    "acme",
+	}
+	for path, want := range expected {
+		if got := lookup(doc, path); got != want {
+			t.Errorf("Expected %s to be %v, got %v", path, want, got)
+		}
+	}
+	if doc["@timestamp"] != "2024-01-02T10:00:00.1Z" {
+		t.Errorf("Expected @timestamp to be the start time, got %v", doc["@timestamp"])
+	}
+	if _, ok := doc["context"]; ok {
+		t.Errorf("Expected no logspan context object, got %v", doc["context"])
+	}
+
+	lines, ok := lookup(doc, "logspan.lines").([]interface{})
+	if !ok || len(lines) != 2 {
+		t.Fatalf("Expected 2 lines under logspan.lines, got %v", lookup(doc, "logspan.lines"))
+	}
+	line := lines[1].(map[string]interface{})
+	for path, want := range map[string]interface{}{
+		"@timestamp":           "2024-01-02T10:00:00.15Z",
+		"log.level":            "warn",
+		"message":              "slow query",
+		"log.origin.file.line": float64(42),
+		"span.id":              "00f067aa0ba902b7",
+		"fields.table":         "users",
+	} {
+		if got := lookup(line, path); got != want {
+			t.Errorf("Expected line %s to be %v, got %v", path, want, got)
+		}
+	}
+}
+
+func TestECSFormatter_FieldMapOverrides(t *testing.T) {
+	f := NewECSFormatterWithFieldMap(map[string]string{
+		"tenant": "organization.name",
+		"path":   "",
+	})
+	f.LinesKey = "event.lines"
+	f.ContextKey = "labels"
+
+	doc := decodeDocument(t, f, testLogOutput())
+
+	if got := lookup(doc, "organization.name"); got != "acme" {
+		t.Errorf("Expected the custom mapping, got %v", got)
+	}
+	if got := lookup(doc, "labels.path"); got != "/api/users" {
+		t.Errorf("Expected an unmapped default key under labels, got %v", got)
+	}
+	if got := lookup(doc, "url.path"); got != nil {
+		t.Errorf("Expected url.path to be unset, got %v", got)
+	}
+	if got := lookup(doc, "http.request.method"); got != "GET" {
+		t.Errorf("Expected the default mappings to remain, got %v", got)
+	}
+	if lines, ok := lookup(doc, "event.lines").([]interface{}); !ok || len(lines) != 2 {
+		t.Errorf("Expected lines under event.lines, got %v", lookup(doc, "event.lines"))
+	}
+	if got := lookup(doc, "event.duration"); got == nil {
+		t.Errorf("Expected event fields next to the lines, got %v", doc["event"])
+	}
+}
+
+func TestECSFormatter_DoesNotModifyContextMaps(t *testing.T) {
+	headers := map[string]interface{}{"Accept": "*/*"}
+	output := testLogOutput()
+	output.Context["request_headers"] = headers
+
+	f := NewECSFormatterWithFieldMap(map[string]string{"tenant": "logspan.context.request_headers.tenant"})
+	doc := decodeDocument(t, f, output)
+
+	if len(headers) != 1 {
+		t.Errorf("Expected the context map to be unchanged, got %v", headers)
+	}
+	if got := lookup(doc, "logspan.context.request_headers.Accept"); got != "*/*" {
+		t.Errorf("Expected the headers under logspan.context, got %v", got)
+	}
+}
+
+func TestECSFormatter_WithoutLines(t *testing.T) {
+	output := &LogOutput{
+		Type:    "request",
+		Runtime: RuntimeInfo{Severity: "DEBUG", StartTime: "invalid", Elapsed: 3},
+	}
+	doc := decodeDocument(t, NewECSFormatter(), output)
+
+	if _, ok := doc["message"]; ok {
+		t.Errorf("Expected no message, got %v", doc["message"])
+	}
+	if got := lookup(doc, "event.duration"); got != float64(3*time.Millisecond) {
+		t.Errorf("Expected the duration from elapsed, got %v", got)
+	}
+	if lines, ok := lookup(doc, "logspan.lines").([]interface{}); !ok || len(lines) != 0 {
+		t.Errorf("Expected an empty lines array, got %v", lookup(doc, "logspan.lines"))
+	}
+}
+
+func TestECSFormatter_Indent(t *testing.T) {
+	f := NewECSFormatter()
+	f.Indent = "  "
+
+	result, err := f.Format(testLogOutput())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(string(result), "\n  \"@timestamp\"") {
+		t.Errorf("Expected indented JSON, got %s", result)
+	}
+}
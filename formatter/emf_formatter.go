@@ -0,0 +1,188 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Metric names written by EMFFormatter
+const (
+	EMFMetricElapsed    = "Elapsed"
+	EMFMetricLineCount  = "LineCount"
+	EMFMetricErrorCount = "ErrorCount"
+	EMFMetricFault      = "Fault"
+)
+
+// EMFDimensionPrefix is prepended to dimension names that clash with a document field or a
+// metric name
+const EMFDimensionPrefix = "dim_"
+
+// emfReservedNames are the top-level names of an EMF document that dimensions must not overwrite
+var emfReservedNames = map[string]bool{
+	"type": true, "context": true, "runtime": true, "_aws": true,
+	EMFMetricElapsed: true, EMFMetricLineCount: true, EMFMetricErrorCount: true, EMFMetricFault: true,
+}
+
+// DefaultEMFStatusCodeKey is the context key holding the HTTP status code
+const DefaultEMFStatusCodeKey = "status_code"
+
+// EMFMetric describes a metric in the _aws metadata block
+type EMFMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit,omitempty"`
+}
+
+// EMFMetricDirective is a metric directive of the _aws metadata block
+type EMFMetricDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []EMFMetric `json:"Metrics"`
+}
+
+// EMFMetadata is the _aws metadata block of an Embedded Metric Format document
+type EMFMetadata struct {
+	Timestamp         int64                `json:"Timestamp"`
+	CloudWatchMetrics []EMFMetricDirective `json:"CloudWatchMetrics"`
+}
+
+// EMFFormatter implements the Formatter interface for AWS CloudWatch Embedded Metric Format
+//
+// The standard document (type, context, runtime) is written with an _aws metadata block and
+// the metric values at the top level, so that CloudWatch extracts metrics from the logs:
+//
+//	{
+//	  "_aws": {
+//	    "Timestamp": 1704189600145,
+//	    "CloudWatchMetrics": [{
+//	      "Namespace": "MyService",
+//	      "Dimensions": [["method", "path"]],
+//	      "Metrics": [{"Name": "Elapsed", "Unit": "Milliseconds"}, {"Name": "LineCount", "Unit": "Count"},
+//	                  {"Name": "ErrorCount", "Unit": "Count"}, {"Name": "Fault", "Unit": "Count"}]
+//	    }]
+//	  },
+//	  "method": "GET", "path": "/api/users",
+//	  "Elapsed": 45, "LineCount": 3, "ErrorCount": 1, "Fault": 0,
+//	  "type": "request", "context": {...}, "runtime": {...}
+//	}
+//
+// Elapsed is runtime.elapsed, LineCount the number of lines, and ErrorCount the number of
+// lines at ERROR or above. Fault is 1 for a 5xx status code and 0 otherwise; it is written only
+// when the context has a status code. Dimension values are taken from the context keys in
+// Dimensions and written as strings; keys missing from the context are left out of the
+// dimension set. A dimension named like a document field (type, context, runtime, _aws) or a
+// metric is written with EMFDimensionPrefix, e.g. dim_type. Timestamp is the end time of the log.
+type EMFFormatter struct {
+	// Namespace is the CloudWatch namespace of the metrics
+	Namespace string
+
+	// Dimensions lists the context keys used as dimensions, in order
+	Dimensions []string
+
+	// StatusCodeKey is the context key of the HTTP status code (default DefaultEMFStatusCodeKey)
+	StatusCodeKey string
+
+	// Indent specifies the indentation string for pretty printing
+	// Empty string means no indentation (compact JSON)
+	Indent string
+}
+
+// NewEMFFormatter creates a new EMFFormatter for the namespace with the given dimension keys
+//
+// Usage:
+//
+//	contextLogger.SetFormatter(formatter.NewEMFFormatter("MyService", "method", "path"))
+func NewEMFFormatter(namespace string, dimensions ...string) *EMFFormatter {
+	return &EMFFormatter{
+		Namespace:     namespace,
+		Dimensions:    dimensions,
+		StatusCodeKey: DefaultEMFStatusCodeKey,
+	}
+}
+
+// Format formats the log output as an Embedded Metric Format document
+func (f *EMFFormatter) Format(output *LogOutput) ([]byte, error) {
+	doc := map[string]interface{}{
+		"type":    output.Type,
+		"context": output.Context,
+		"runtime": output.Runtime,
+	}
+
+	// Dimensions
+	dimensions := make([]string, 0, len(f.Dimensions))
+	for _, key := range f.Dimensions {
+		value, ok := output.Context[key]
+		if !ok || value == nil {
+			continue
+		}
+		name := emfDimensionName(key)
+		doc[name] = fmt.Sprint(value)
+		dimensions = append(dimensions, name)
+	}
+
+	// Metrics
+	errorCount := 0
+	for _, entry := range output.Runtime.Lines {
+		if levelRank(entry.Level) >= levelRank("ERROR") {
+			errorCount++
+		}
+	}
+	metrics := []EMFMetric{
+		{Name: EMFMetricElapsed, Unit: "Milliseconds"},
+		{Name: EMFMetricLineCount, Unit: "Count"},
+		{Name: EMFMetricErrorCount, Unit: "Count"},
+	}
+	doc[EMFMetricElapsed] = output.Runtime.Elapsed
+	doc[EMFMetricLineCount] = len(output.Runtime.Lines)
+	doc[EMFMetricErrorCount] = errorCount
+
+	if status, ok := statusCode(output.Context[defaultString(f.StatusCodeKey, DefaultEMFStatusCodeKey)]); ok {
+		fault := 0
+		if status >= 500 {
+			fault = 1
+		}
+		metrics = append(metrics, EMFMetric{Name: EMFMetricFault, Unit: "Count"})
+		doc[EMFMetricFault] = fault
+	}
+
+	timestamp := time.Now()
+	if end, err := time.Parse(time.RFC3339Nano, output.Runtime.EndTime); err == nil {
+		timestamp = end
+	}
+	doc["_aws"] = EMFMetadata{
+		Timestamp: timestamp.UnixMilli(),
+		CloudWatchMetrics: []EMFMetricDirective{{
+			Namespace:  f.Namespace,
+			Dimensions: [][]string{dimensions},
+			Metrics:    metrics,
+		}},
+	}
+
+	if f.Indent == "" {
+		return json.Marshal(doc)
+	}
+	return json.MarshalIndent(doc, "", f.Indent)
+}
+
+// emfDimensionName returns the document name of a dimension, prefixed when the key clashes
+// with a reserved name
+func emfDimensionName(key string) string {
+	if emfReservedNames[key] {
+		return EMFDimensionPrefix + key
+	}
+	return key
+}
+
+// statusCode converts a context value holding an HTTP status code to an int
+func statusCode(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,142 @@
+package formatter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEMFFormatter_Format(t *testing.T) {
+	output := testLogOutput()
+	output.Context["status_code"] = 503
+	output.Runtime.Lines = append(output.Runtime.Lines,
+		&LogEntry{Level: "ERROR", Message: "upstream failed"},
+		&LogEntry{Level: "CRITICAL", Message: "giving up"},
+	)
+
+	doc := decodeDocument(t, NewEMFFormatter("MyService", "method", "path"), output)
+
+	if doc["method"] != "GET" || doc["path"] != "/api/users" {
+		t.Errorf("Expected dimension values at the top level, got method=%v path=%v", doc["method"], doc["path"])
+	}
+	for name, want := range map[string]float64{"Elapsed": 45, "LineCount": 4, "ErrorCount": 2, "Fault": 1} {
+		if doc[name] != want {
+			t.Errorf("Expected %s to be %v, got %v", name, want, doc[name])
+		}
+	}
+	if doc["type"] != "request" || lookup(doc, "context.tenant") != "acme" || lookup(doc, "runtime.severity") != "WARN" {
+		t.Errorf("Expected the standard document fields, got %v", doc)
+	}
+
+	var metadata struct {
+		AWS EMFMetadata `json:"_aws"`
+	}
+	result, _ := NewEMFFormatter("MyService", "method", "path").Format(output)
+	if err := json.Unmarshal(result, &metadata); err != nil {
+		t.Fatalf("Failed to decode _aws: %v", err)
+	}
+
+	expectedTimestamp := time.Date(2024, 1, 2, 10, 0, 0, 145123400, time.UTC).UnixMilli()
+	if metadata.AWS.Timestamp != expectedTimestamp {
+		t.Errorf("Expected the end time as timestamp, got %d", metadata.AWS.Timestamp)
+	}
+	if len(metadata.AWS.CloudWatchMetrics) != 1 {
+		t.Fatalf("Expected one metric directive, got %v", metadata.AWS.CloudWatchMetrics)
+	}
+	directive := metadata.AWS.CloudWatchMetrics[0]
+	if directive.Namespace != "MyService" {
+		t.Errorf("Unexpected namespace: %s", directive.Namespace)
+	}
+	if len(directive.Dimensions) != 1 || len(directive.Dimensions[0]) != 2 ||
+		directive.Dimensions[0][0] != "method" || directive.Dimensions[0][1] != "path" {
+		t.Errorf("Unexpected dimensions: %v", directive.Dimensions)
+	}
+	expectedMetrics := []EMFMetric{
+		{Name: "Elapsed", Unit: "Milliseconds"},
+		{Name: "LineCount", Unit: "Count"},
+		{Name: "ErrorCount", Unit: "Count"},
+		{Name: "Fault", Unit: "Count"},
+	}
+	if len(directive.Metrics) != len(expectedMetrics) {
+		t.Fatalf("Unexpected metrics: %v", directive.Metrics)
+	}
+	for i, metric := range expectedMetrics {
+		if directive.Metrics[i] != metric {
+			t.Errorf("Expected metric %v, got %v", metric, directive.Metrics[i])
+		}
+	}
+}
+
+func TestEMFFormatter_MissingDimensionsAndStatus(t *testing.T) {
+	output := &LogOutput{
+		Type:    "batch",
+		Context: map[string]interface{}{"job": "sync", "shard": 3},
+		Runtime: RuntimeInfo{Severity: "INFO", Elapsed: 12, EndTime: "invalid"},
+	}
+
+	before := time.Now().UnixMilli()
+	doc := decodeDocument(t, NewEMFFormatter("Jobs", "job", "region", "shard"), output)
+
+	if doc["shard"] != "3" {
+		t.Errorf("Expected dimension values as strings, got %v", doc["shard"])
+	}
+	if _, ok := doc["Fault"]; ok {
+		t.Errorf("Expected no Fault metric without a status code, got %v", doc["Fault"])
+	}
+
+	aws := doc["_aws"].(map[string]interface{})
+	directive := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	dimensions := directive["Dimensions"].([]interface{})[0].([]interface{})
+	if len(dimensions) != 2 || dimensions[0] != "job" || dimensions[1] != "shard" {
+		t.Errorf("Expected missing dimensions to be left out, got %v", dimensions)
+	}
+	if metrics := directive["Metrics"].([]interface{}); len(metrics) != 3 {
+		t.Errorf("Expected 3 metrics, got %v", metrics)
+	}
+	if timestamp := aws["Timestamp"].(float64); int64(timestamp) < before {
+		t.Errorf("Expected the current time as fallback timestamp, got %v", timestamp)
+	}
+}
+
+func TestEMFFormatter_StatusCodeKey(t *testing.T) {
+	output := &LogOutput{
+		Type:    "request",
+		Context: map[string]interface{}{"status": float64(200)},
+		Runtime: RuntimeInfo{Severity: "INFO"},
+	}
+
+	f := NewEMFFormatter("MyService")
+	f.StatusCodeKey = "status"
+	doc := decodeDocument(t, f, output)
+
+	if doc["Fault"] != float64(0) {
+		t.Errorf("Expected Fault 0 for a 200 status, got %v", doc["Fault"])
+	}
+}
+
+func TestEMFFormatter_ReservedDimensionNames(t *testing.T) {
+	output := &LogOutput{
+		Type:    "request",
+		Context: map[string]interface{}{"type": "x", "Elapsed": "slow", "region": "ap-northeast-1"},
+		Runtime: RuntimeInfo{Severity: "INFO", Elapsed: 45},
+	}
+
+	doc := decodeDocument(t, NewEMFFormatter("MyService", "type", "Elapsed", "region"), output)
+
+	if doc["type"] != "request" {
+		t.Errorf("Expected the document type to be kept, got %v", doc["type"])
+	}
+	if doc["Elapsed"] != float64(45) {
+		t.Errorf("Expected the Elapsed metric to be kept, got %v", doc["Elapsed"])
+	}
+	if doc["dim_type"] != "x" || doc["dim_Elapsed"] != "slow" || doc["region"] != "ap-northeast-1" {
+		t.Errorf("Expected clashing dimensions to be prefixed, got %v", doc)
+	}
+
+	aws := doc["_aws"].(map[string]interface{})
+	directive := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	dimensions := directive["Dimensions"].([]interface{})[0].([]interface{})
+	if len(dimensions) != 3 || dimensions[0] != "dim_type" || dimensions[1] != "dim_Elapsed" || dimensions[2] != "region" {
+		t.Errorf("Expected the prefixed names in the dimension set, got %v", dimensions)
+	}
+}
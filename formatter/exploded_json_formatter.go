@@ -0,0 +1,126 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ExplodedEntry is the document written by ExplodedJSONFormatter for each log entry
+// The entry fields (timestamp, level, message, ...) are written at the top level next to
+// the shared fields of the aggregated log.
+type ExplodedEntry struct {
+	Type    string                 `json:"type"`
+	Context map[string]interface{} `json:"context"`
+
+	// AggregateID is shared by all documents of an aggregated log
+	AggregateID string `json:"aggregateId"`
+
+	// Seq is the 1-based position of the entry in the aggregated log
+	Seq int `json:"seq"`
+
+	// Total is the number of entries in the aggregated log
+	Total int `json:"total"`
+
+	// Severity is the aggregated severity of the whole log
+	Severity string `json:"severity"`
+
+	// DroppedLines is the number of lines of the aggregated log dropped by the limits
+	DroppedLines int `json:"droppedLines,omitempty"`
+
+	*LogEntry
+}
+
+// ExplodedJSONFormatter implements the RecordFormatter interface and writes each log entry
+// as its own JSON document, for backends that index only flat documents or truncate large ones
+//
+//	{"type":"request","context":{"path":"/api/users"},"aggregateId":"9f86d081884c7d65","seq":1,"total":2,"severity":"WARN","timestamp":"...","level":"INFO","message":"Request started"}
+//	{"type":"request","context":{"path":"/api/users"},"aggregateId":"9f86d081884c7d65","seq":2,"total":2,"severity":"WARN","timestamp":"...","level":"WARN","message":"slow query"}
+//
+// The documents of an aggregated log can be reassembled with aggregateId and seq.
+// An output without entries produces a single document with seq and total set to 0.
+// Spans are not written.
+type ExplodedJSONFormatter struct {
+	// Indent specifies the indentation string for pretty printing
+	// Empty string means no indentation (compact JSON)
+	Indent string
+}
+
+// NewExplodedJSONFormatter creates a new ExplodedJSONFormatter instance
+func NewExplodedJSONFormatter() *ExplodedJSONFormatter {
+	return &ExplodedJSONFormatter{}
+}
+
+// NewExplodedJSONFormatterWithIndent creates a new ExplodedJSONFormatter with indentation
+func NewExplodedJSONFormatterWithIndent(indent string) *ExplodedJSONFormatter {
+	return &ExplodedJSONFormatter{
+		Indent: indent,
+	}
+}
+
+// Format formats the log output as JSON documents separated by newlines
+func (f *ExplodedJSONFormatter) Format(output *LogOutput) ([]byte, error) {
+	records, err := f.FormatRecords(output)
+	if err != nil {
+		return nil, err
+	}
+	return joinRecords(records), nil
+}
+
+// FormatRecords formats each log entry as a separate JSON document
+func (f *ExplodedJSONFormatter) FormatRecords(output *LogOutput) ([]Record, error) {
+	aggregateID := newAggregateID()
+	lines := output.Runtime.Lines
+
+	if len(lines) == 0 {
+		data, err := f.marshal(&ExplodedEntry{
+			Type:         output.Type,
+			Context:      output.Context,
+			AggregateID:  aggregateID,
+			Severity:     output.Runtime.Severity,
+			DroppedLines: output.Runtime.DroppedLines,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []Record{{Level: output.Runtime.Severity, Data: data}}, nil
+	}
+
+	records := make([]Record, 0, len(lines))
+	for i, entry := range lines {
+		data, err := f.marshal(&ExplodedEntry{
+			Type:         output.Type,
+			Context:      output.Context,
+			AggregateID:  aggregateID,
+			Seq:          i + 1,
+			Total:        len(lines),
+			Severity:     output.Runtime.Severity,
+			DroppedLines: output.Runtime.DroppedLines,
+			LogEntry:     entry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, Record{Level: entry.Level, Data: data})
+	}
+	return records, nil
+}
+
+// marshal encodes a document with the configured indentation
+func (f *ExplodedJSONFormatter) marshal(entry *ExplodedEntry) ([]byte, error) {
+	if f.Indent == "" {
+		return json.Marshal(entry)
+	}
+	return json.MarshalIndent(entry, "", f.Indent)
+}
+
+// joinRecords joins the data of the records with newlines
+func joinRecords(records []Record) []byte {
+	var buf bytes.Buffer
+	for i, record := range records {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(record.Data)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,141 @@
+package formatter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplodedJSONFormatter_FormatRecords(t *testing.T) {
+	formatter := NewExplodedJSONFormatter()
+	output := testLogOutput()
+
+	records, err := formatter.FormatRecords(output)
+	if err != nil {
+		t.Fatalf("FormatRecords failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	var aggregateID string
+	for i, record := range records {
+		if strings.Contains(string(record.Data), "\n") {
+			t.Errorf("Expected compact JSON, got %s", record.Data)
+		}
+
+		var doc map[string]interface{}
+		if err := json.Unmarshal(record.Data, &doc); err != nil {
+			t.Fatalf("Record %d is not valid JSON: %v", i, err)
+		}
+		if doc["type"] != "request" || doc["severity"] != "WARN" {
+			t.Errorf("Expected type and aggregated severity, got %v", doc)
+		}
+		if doc["seq"] != float64(i+1) || doc["total"] != float64(2) {
+			t.Errorf("Expected seq %d of 2, got %v of %v", i+1, doc["seq"], doc["total"])
+		}
+		context, ok := doc["context"].(map[string]interface{})
+		if !ok || context["path"] != "/api/users" {
+			t.Errorf("Expected the shared context, got %v", doc["context"])
+		}
+		if _, ok := doc["lines"]; ok {
+			t.Errorf("Expected no lines array, got %v", doc)
+		}
+
+		id, _ := doc["aggregateId"].(string)
+		if len(id) != 16 {
+			t.Errorf("Expected a 16 character aggregateId, got %q", id)
+		}
+		if i == 0 {
+			aggregateID = id
+		} else if id != aggregateID {
+			t.Errorf("Expected a shared aggregateId, got %q and %q", aggregateID, id)
+		}
+	}
+
+	// Entry fields are written at the top level; the level of each record is the entry level
+	var second map[string]interface{}
+	_ = json.Unmarshal(records[1].Data, &second)
+	if second["message"] != "slow query" || second["level"] != "WARN" || second["filename"] != "db.go" {
+		t.Errorf("Expected the entry fields at the top level, got %v", second)
+	}
+	if fields, ok := second["fields"].(map[string]interface{}); !ok || fields["table"] != "users" {
+		t.Errorf("Expected the line fields, got %v", second["fields"])
+	}
+	if records[0].Level != "INFO" || records[1].Level != "WARN" {
+		t.Errorf("Expected record levels INFO and WARN, got %s and %s", records[0].Level, records[1].Level)
+	}
+}
+
+func TestExplodedJSONFormatter_WithoutLines(t *testing.T) {
+	formatter := NewExplodedJSONFormatter()
+	output := &LogOutput{
+		Type:    "batch",
+		Context: map[string]interface{}{"job": "sync"},
+		Runtime: RuntimeInfo{Severity: "DEBUG", Truncated: true, DroppedLines: 2},
+	}
+
+	records, err := formatter.FormatRecords(output)
+	if err != nil {
+		t.Fatalf("FormatRecords failed: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected a single record, got %d", len(records))
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(records[0].Data, &doc); err != nil {
+		t.Fatalf("Record is not valid JSON: %v", err)
+	}
+	if doc["type"] != "batch" || doc["seq"] != float64(0) || doc["total"] != float64(0) {
+		t.Errorf("Unexpected document: %v", doc)
+	}
+	if _, ok := doc["message"]; ok {
+		t.Errorf("Expected no entry fields, got %v", doc)
+	}
+	if doc["droppedLines"] != float64(2) {
+		t.Errorf("Expected droppedLines 2, got %v", doc)
+	}
+	if records[0].Level != "DEBUG" {
+		t.Errorf("Expected the aggregated severity as level, got %s", records[0].Level)
+	}
+}
+
+func TestExplodedJSONFormatter_Format(t *testing.T) {
+	output := &LogOutput{
+		Type: "request",
+		Runtime: RuntimeInfo{
+			Severity: "INFO",
+			Lines: []*LogEntry{
+				{Timestamp: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), Level: "INFO", Message: "one"},
+				{Timestamp: time.Date(2024, 1, 2, 10, 0, 1, 0, time.UTC), Level: "INFO", Message: "two"},
+			},
+		},
+	}
+
+	result, err := NewExplodedJSONFormatter().Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	lines := strings.Split(string(result), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 newline-separated documents, got %q", result)
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("Expected a JSON document, got %s", line)
+		}
+	}
+}
+
+func TestExplodedJSONFormatter_Indent(t *testing.T) {
+	records, err := NewExplodedJSONFormatterWithIndent("  ").FormatRecords(testLogOutput())
+	if err != nil {
+		t.Fatalf("FormatRecords failed: %v", err)
+	}
+	if !strings.Contains(string(records[0].Data), "\n  \"type\": \"request\"") {
+		t.Errorf("Expected indented JSON, got %s", records[0].Data)
+	}
+}
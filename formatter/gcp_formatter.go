@@ -0,0 +1,218 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Special keys recognised by Google Cloud Logging in structured JSON logs
+const (
+	GCPKeyTrace          = "logging.googleapis.com/trace"
+	GCPKeySpanID         = "logging.googleapis.com/spanId"
+	GCPKeyTraceSampled   = "logging.googleapis.com/trace_sampled"
+	GCPKeySourceLocation = "logging.googleapis.com/sourceLocation"
+)
+
+// Default context keys read by GCPFormatter for trace information
+const (
+	DefaultGCPTraceKey      = "trace_id"
+	DefaultGCPSpanKey       = "span_id"
+	DefaultGCPTraceFlagsKey = "trace_flags"
+)
+
+// DefaultGCPHTTPRequestMap maps the context fields written by the HTTP middleware onto the
+// fields of the Cloud Logging httpRequest object
+var DefaultGCPHTTPRequestMap = map[string]string{
+	"method":         "requestMethod",
+	"url":            "requestUrl",
+	"status_code":    "status",
+	"user_agent":     "userAgent",
+	"remote_addr":    "remoteIp",
+	"proto":          "protocol",
+	"request_bytes":  "requestSize",
+	"response_bytes": "responseSize",
+}
+
+// GCPSourceLocation is the value of logging.googleapis.com/sourceLocation
+type GCPSourceLocation struct {
+	File     string `json:"file,omitempty"`
+	Line     string `json:"line,omitempty"`
+	Function string `json:"function,omitempty"`
+}
+
+// GCPTimestamp is the timestamp of a log in seconds and nanoseconds since the Unix epoch
+type GCPTimestamp struct {
+	Seconds int64 `json:"seconds"`
+	Nanos   int   `json:"nanos"`
+}
+
+// GCPFormatter implements the Formatter interface for Google Cloud Logging structured logs
+//
+// The standard logspan document (type, context, runtime) is written with the special keys
+// Cloud Logging recognises when the log is written to standard output on GKE or Cloud Run:
+//
+//	{
+//	  "severity": "WARNING",
+//	  "message": "slow query",
+//	  "timestamp": {"seconds": 1704189600, "nanos": 100000000},
+//	  "httpRequest": {"requestMethod": "GET", "requestUrl": "/api/users?id=1", "status": 200, "latency": "0.045s"},
+//	  "logging.googleapis.com/trace": "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+//	  "logging.googleapis.com/spanId": "00f067aa0ba902b7",
+//	  "logging.googleapis.com/trace_sampled": true,
+//	  "logging.googleapis.com/sourceLocation": {"file": "db.go", "line": "42", "function": "main.query"},
+//	  "type": "request",
+//	  "context": {...},
+//	  "runtime": {...}
+//	}
+//
+// severity is the aggregated severity mapped onto Cloud Logging names (WARN becomes WARNING),
+// timestamp is the start time of the log, and message and sourceLocation come from the first
+// line with the highest severity. httpRequest is built from the HTTP middleware context fields
+// with DefaultGCPHTTPRequestMap and FieldMap; its latency is the duration of the log.
+// The trace field is written only when ProjectID is set.
+type GCPFormatter struct {
+	// ProjectID is the Google Cloud project used to build the trace resource name
+	ProjectID string
+
+	// FieldMap maps context keys to httpRequest fields, in addition to DefaultGCPHTTPRequestMap
+	// Mapping a key to an empty string excludes it from httpRequest
+	FieldMap map[string]string
+
+	// TraceKey, SpanKey and TraceFlagsKey are the context keys holding the W3C trace ID,
+	// span ID and trace flags (defaults DefaultGCPTraceKey, DefaultGCPSpanKey and
+	// DefaultGCPTraceFlagsKey)
+	TraceKey      string
+	SpanKey       string
+	TraceFlagsKey string
+
+	// Indent specifies the indentation string for pretty printing
+	// Empty string means no indentation (compact JSON)
+	Indent string
+}
+
+// NewGCPFormatter creates a new GCPFormatter for the given Google Cloud project
+func NewGCPFormatter(projectID string) *GCPFormatter {
+	return &GCPFormatter{
+		ProjectID:     projectID,
+		TraceKey:      DefaultGCPTraceKey,
+		SpanKey:       DefaultGCPSpanKey,
+		TraceFlagsKey: DefaultGCPTraceFlagsKey,
+	}
+}
+
+// Format formats the log output as a Cloud Logging structured JSON document
+func (f *GCPFormatter) Format(output *LogOutput) ([]byte, error) {
+	doc := map[string]interface{}{
+		"severity": GCPSeverity(output.Runtime.Severity),
+		"type":     output.Type,
+		"context":  output.Context,
+		"runtime":  output.Runtime,
+	}
+
+	if start, err := time.Parse(time.RFC3339Nano, output.Runtime.StartTime); err == nil {
+		doc["timestamp"] = GCPTimestamp{Seconds: start.Unix(), Nanos: start.Nanosecond()}
+	}
+
+	if entry := mostSevereEntry(output.Runtime.Lines); entry != nil {
+		doc["message"] = entry.Message
+		if entry.Filename != "" || entry.Funcname != "" {
+			location := GCPSourceLocation{File: entry.Filename, Function: entry.Funcname}
+			if entry.Filename != "" {
+				location.Line = strconv.Itoa(entry.Fileline)
+			}
+			doc[GCPKeySourceLocation] = location
+		}
+	}
+
+	if httpRequest := f.httpRequest(output); httpRequest != nil {
+		doc["httpRequest"] = httpRequest
+	}
+
+	traceID := contextString(output.Context, defaultString(f.TraceKey, DefaultGCPTraceKey))
+	if traceID != "" && f.ProjectID != "" {
+		doc[GCPKeyTrace] = fmt.Sprintf("projects/%s/traces/%s", f.ProjectID, traceID)
+	}
+	if spanID := contextString(output.Context, defaultString(f.SpanKey, DefaultGCPSpanKey)); spanID != "" {
+		doc[GCPKeySpanID] = spanID
+	}
+	if flags := contextString(output.Context, defaultString(f.TraceFlagsKey, DefaultGCPTraceFlagsKey)); flags != "" {
+		if value, err := strconv.ParseUint(flags, 16, 8); err == nil {
+			doc[GCPKeyTraceSampled] = value&0x01 != 0
+		}
+	}
+
+	if f.Indent == "" {
+		return json.Marshal(doc)
+	}
+	return json.MarshalIndent(doc, "", f.Indent)
+}
+
+// httpRequest builds the httpRequest object from the context, or returns nil if the context
+// has no HTTP fields
+func (f *GCPFormatter) httpRequest(output *LogOutput) map[string]interface{} {
+	request := make(map[string]interface{})
+	for key, value := range output.Context {
+		field, ok := f.FieldMap[key]
+		if !ok {
+			field = DefaultGCPHTTPRequestMap[key]
+		}
+		if field == "" {
+			continue
+		}
+
+		switch field {
+		case "remoteIp", "serverIp":
+			// The HTTP middleware records host:port
+			if s, ok := value.(string); ok {
+				if host, _, err := net.SplitHostPort(s); err == nil {
+					value = host
+				}
+			}
+		case "requestSize", "responseSize", "cacheFillBytes":
+			// int64 fields are strings in the JSON representation
+			value = fmt.Sprint(value)
+		}
+		request[field] = value
+	}
+	if len(request) == 0 {
+		return nil
+	}
+
+	request["latency"] = gcpDuration(time.Duration(eventDuration(output.Runtime)))
+	return request
+}
+
+// GCPSeverity maps a logspan level name onto a Cloud Logging severity name
+// Unknown levels map to DEFAULT.
+func GCPSeverity(level string) string {
+	switch level {
+	case "DEBUG", "INFO", "ERROR", "CRITICAL":
+		return level
+	case "WARN":
+		return "WARNING"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// gcpDuration formats a duration as a Cloud Logging duration string ("0.045s")
+func gcpDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// contextString returns the context value of key if it is a string
+func contextString(context map[string]interface{}, key string) string {
+	s, _ := context[key].(string)
+	return s
+}
+
+// defaultString returns s, or def if s is empty
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
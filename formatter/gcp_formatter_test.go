@@ -0,0 +1,129 @@
+package formatter
+
+import (
+	"testing"
+	"time"
+)
+
+// gcpTestOutput returns a log output with HTTP middleware and trace context fields
+func gcpTestOutput() *LogOutput {
+	output := testLogOutput()
+	output.Context = map[string]interface{}{
+		"method":         "GET",
+		"url":            "/api/users?id=1",
+		"path":           "/api/users",
+		"status_code":    200,
+		"user_agent":     "curl/8.0",
+		"remote_addr":    "192.0.2.1:51234",
+		"proto":          "HTTP/1.1",
+		"request_bytes":  int64(0),
+		"response_bytes": int64(512),
+		"trace_id":       "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":        "00f067aa0ba902b7",
+		"trace_flags":    "01",
+	}
+	return output
+}
+
+func TestGCPFormatter_Format(t *testing.T) {
+	doc := decodeDocument(t, NewGCPFormatter("my-project"), gcpTestOutput())
+
+	expected := map[string]interface{}{
+		"severity":                  "WARNING",
+		"message":                   "slow query",
+		"timestamp.seconds":         float64(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC).Unix()),
+		"timestamp.nanos":           float64(100000000),
+		"httpRequest.requestMethod": "GET",
+		"httpRequest.requestUrl":    "/api/users?id=1",
+		"httpRequest.status":        float64(200),
+		"httpRequest.userAgent":     "curl/8.0",
+		"httpRequest.remoteIp":      "192.0.2.1",
+		"httpRequest.protocol":      "HTTP/1.1",
+		"httpRequest.requestSize":   "0",
+		"httpRequest.responseSize":  "512",
+		"httpRequest.latency":       "0.0451234s",
+		"type":                      "request",
+		"context.path":              "/api/users",
+		"runtime.severity":          "WARN",
+	}
+	for path, want := range expected {
+		if got := lookup(doc, path); got != want {
+			t.Errorf("Expected %s to be %v, got %v", path, want, got)
+		}
+	}
+
+	if got := doc[GCPKeyTrace]; got != "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Unexpected trace: %v", got)
+	}
+	if got := doc[GCPKeySpanID]; got != "00f067aa0ba902b7" {
+		t.Errorf("Unexpected span ID: %v", got)
+	}
+	if got := doc[GCPKeyTraceSampled]; got != true {
+		t.Errorf("Expected trace_sampled to be true, got %v", got)
+	}
+
+	location, ok := doc[GCPKeySourceLocation].(map[string]interface{})
+	if !ok || location["file"] != "db.go" || location["line"] != "42" || location["function"] != "main.query" {
+		t.Errorf("Unexpected source location: %v", doc[GCPKeySourceLocation])
+	}
+}
+
+func TestGCPFormatter_WithoutProjectOrHTTPFields(t *testing.T) {
+	output := &LogOutput{
+		Type:    "batch",
+		Context: map[string]interface{}{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "trace_flags": "00"},
+		Runtime: RuntimeInfo{Severity: "INFO", StartTime: "invalid"},
+	}
+	doc := decodeDocument(t, &GCPFormatter{}, output)
+
+	for _, key := range []string{GCPKeyTrace, GCPKeySpanID, GCPKeySourceLocation, "httpRequest", "timestamp", "message"} {
+		if _, ok := doc[key]; ok {
+			t.Errorf("Expected no %s, got %v", key, doc[key])
+		}
+	}
+	if got := doc[GCPKeyTraceSampled]; got != false {
+		t.Errorf("Expected trace_sampled to be false, got %v", got)
+	}
+	if doc["severity"] != "INFO" {
+		t.Errorf("Expected INFO severity, got %v", doc["severity"])
+	}
+}
+
+func TestGCPFormatter_FieldMapAndKeys(t *testing.T) {
+	output := gcpTestOutput()
+	output.Context["referer"] = "https://example.com/"
+	output.Context["otel_trace"] = "0af7651916cd43dd8448eb211c80319c"
+
+	f := NewGCPFormatter("my-project")
+	f.FieldMap = map[string]string{"referer": "referer", "user_agent": ""}
+	f.TraceKey = "otel_trace"
+
+	doc := decodeDocument(t, f, output)
+
+	if got := lookup(doc, "httpRequest.referer"); got != "https://example.com/" {
+		t.Errorf("Expected the custom mapping, got %v", got)
+	}
+	if got := lookup(doc, "httpRequest.userAgent"); got != nil {
+		t.Errorf("Expected userAgent to be excluded, got %v", got)
+	}
+	if got := doc[GCPKeyTrace]; got != "projects/my-project/traces/0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("Expected the trace from the custom key, got %v", got)
+	}
+}
+
+func TestGCPSeverity(t *testing.T) {
+	tests := map[string]string{
+		"DEBUG":    "DEBUG",
+		"INFO":     "INFO",
+		"WARN":     "WARNING",
+		"ERROR":    "ERROR",
+		"CRITICAL": "CRITICAL",
+		"":         "DEFAULT",
+		"TRACE":    "DEFAULT",
+	}
+	for level, want := range tests {
+		if got := GCPSeverity(level); got != want {
+			t.Errorf("GCPSeverity(%q) = %s, expected %s", level, got, want)
+		}
+	}
+}
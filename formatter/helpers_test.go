@@ -0,0 +1,76 @@
+package formatter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLogOutput returns a request log output shared by the formatter tests: HTTP middleware
+// context fields, a nested and a slice context value, and two lines, the second with fields,
+// source info and a span
+func testLogOutput() *LogOutput {
+	return &LogOutput{
+		Type: "request",
+		Context: map[string]interface{}{
+			"method":      "GET",
+			"path":        "/api/users",
+			"status_code": 200,
+			"trace_id":    "4bf92f3577b34da6a3ce929d0e0e4736",
+			"tenant":      "acme",
+			"headers":     map[string]interface{}{"X-Request-Id": "abc", "Accept": "*/*"},
+			"tags":        []string{"a", "b"},
+		},
+		Runtime: RuntimeInfo{
+			Severity:  "WARN",
+			StartTime: "2024-01-02T10:00:00.1Z",
+			EndTime:   "2024-01-02T10:00:00.1451234Z",
+			Elapsed:   45,
+			Lines: []*LogEntry{
+				{
+					Timestamp: time.Date(2024, 1, 2, 10, 0, 0, 123000000, time.UTC),
+					Level:     "INFO",
+					Message:   "Request started",
+				},
+				{
+					Timestamp: time.Date(2024, 1, 2, 10, 0, 0, 150000000, time.UTC),
+					Level:     "WARN",
+					Message:   "slow query",
+					Fields:    map[string]interface{}{"table": "users", "query": "SELECT 1", "rows": 3},
+					Funcname:  "main.query",
+					Filename:  "db.go",
+					Fileline:  42,
+					SpanID:    "00f067aa0ba902b7",
+				},
+			},
+		},
+	}
+}
+
+// decodeDocument formats the output and decodes the resulting JSON document
+func decodeDocument(t *testing.T, f Formatter, output *LogOutput) map[string]interface{} {
+	t.Helper()
+	result, err := f.Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(result, &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, result)
+	}
+	return doc
+}
+
+// lookup returns the value at a dotted path of nested objects
+func lookup(doc map[string]interface{}, path string) interface{} {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
@@ -12,6 +12,15 @@ type LogEntry struct {
 	Funcname  string    `json:"funcname,omitempty"`
 	Filename  string    `json:"filename,omitempty"`
 	Fileline  int       `json:"fileline,omitempty"`
+
+	// Fields holds structured key/value data attached to this entry only
+	Fields map[string]interface{} `json:"fields,omitempty"`
+
+	// SpanID is the identifier of the span that was active when the entry was logged
+	SpanID string `json:"spanId,omitempty"`
+
+	// Truncated is set when the message was shortened to the maximum message length
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // LogOutput represents the complete log output structure
@@ -28,6 +37,26 @@ type RuntimeInfo struct {
 	EndTime   string      `json:"endTime"`
 	Elapsed   int64       `json:"elapsed"`
 	Lines     []*LogEntry `json:"lines"`
+	Spans     []*SpanInfo `json:"spans,omitempty"`
+
+	// Truncated is set when lines were dropped to respect the line or size limits
+	Truncated bool `json:"truncated,omitempty"`
+
+	// DroppedLines is the number of lines dropped; Severity still accounts for them
+	DroppedLines int `json:"droppedLines,omitempty"`
+}
+
+// SpanInfo describes a timed sub-operation and its nested spans
+type SpanInfo struct {
+	ID        string                 `json:"id"`
+	ParentID  string                 `json:"parentId,omitempty"`
+	Name      string                 `json:"name"`
+	Severity  string                 `json:"severity"`
+	StartTime string                 `json:"startTime"`
+	EndTime   string                 `json:"endTime,omitempty"` // Empty while the span is still open
+	Elapsed   int64                  `json:"elapsed"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Children  []*SpanInfo            `json:"children,omitempty"`
 }
 
 // Formatter defines the interface for log formatters
@@ -35,3 +64,22 @@ type Formatter interface {
 	// Format formats the log output and returns the formatted bytes
 	Format(output *LogOutput) ([]byte, error)
 }
+
+// Record is one document produced by a RecordFormatter
+type Record struct {
+	// Level is the severity of the record, used by outputs that filter or prioritize documents
+	Level string
+
+	// Data is the formatted document, without a trailing newline
+	Data []byte
+}
+
+// RecordFormatter is a Formatter that can split a log output into several documents
+// Loggers and sinks call FormatRecords instead of Format and write each record separately,
+// so that each record reaches the output as its own line.
+type RecordFormatter interface {
+	Formatter
+
+	// FormatRecords formats the log output as one or more records
+	FormatRecords(output *LogOutput) ([]Record, error)
+}
@@ -143,3 +143,50 @@ func containsIndentation(s string) bool {
 	}
 	return false
 }
+
+func TestJSONFormatter_FormatLineFields(t *testing.T) {
+	formatter := NewJSONFormatter()
+
+	output := &LogOutput{
+		Type:    "request",
+		Context: map[string]interface{}{},
+		Runtime: RuntimeInfo{
+			Severity: "INFO",
+			Lines: []*LogEntry{
+				{
+					Timestamp: time.Date(2023, 10, 27, 10, 0, 0, 0, time.UTC),
+					Level:     "INFO",
+					Message:   "query executed",
+					Fields:    map[string]interface{}{"db_rows": 12},
+				},
+				{
+					Timestamp: time.Date(2023, 10, 27, 10, 0, 1, 0, time.UTC),
+					Level:     "INFO",
+					Message:   "no fields",
+				},
+			},
+		},
+	}
+
+	result, err := formatter.Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	var parsed struct {
+		Runtime struct {
+			Lines []map[string]interface{} `json:"lines"`
+		} `json:"runtime"`
+	}
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		t.Fatalf("Result is not valid JSON: %v", err)
+	}
+
+	fields, ok := parsed.Runtime.Lines[0]["fields"].(map[string]interface{})
+	if !ok || fields["db_rows"] != float64(12) {
+		t.Errorf("Expected fields.db_rows=12, got %v", parsed.Runtime.Lines[0]["fields"])
+	}
+	if _, exists := parsed.Runtime.Lines[1]["fields"]; exists {
+		t.Error("Expected fields to be omitted for a line without fields")
+	}
+}
@@ -0,0 +1,313 @@
+package formatter
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LogfmtMode selects how LogfmtFormatter writes an aggregated log
+type LogfmtMode int
+
+const (
+	// LogfmtAggregate writes a single line with the context fields and summarised runtime info
+	LogfmtAggregate LogfmtMode = iota
+
+	// LogfmtExploded writes one line per entry with the context fields repeated
+	LogfmtExploded
+)
+
+// Keys written by LogfmtFormatter in addition to the context and line fields
+const (
+	LogfmtKeyType        = "type"
+	LogfmtKeySeverity    = "severity"
+	LogfmtKeyStartTime   = "start_time"
+	LogfmtKeyEndTime     = "end_time"
+	LogfmtKeyElapsed     = "elapsed"
+	LogfmtKeyLines       = "lines"
+	LogfmtKeyMessage     = "msg"
+	LogfmtKeyTime        = "time"
+	LogfmtKeyLevel       = "level"
+	LogfmtKeyAggregateID = "aggregate_id"
+	LogfmtKeySeq         = "seq"
+	LogfmtKeySpanID      = "span_id"
+	LogfmtKeyFunc        = "func"
+	LogfmtKeySource      = "source"
+	LogfmtKeyTruncated   = "truncated"
+	LogfmtKeyDropped     = "dropped_lines"
+)
+
+// Prefixes added to context and line field keys that collide with the keys above
+const (
+	logfmtContextPrefix = "context."
+	logfmtFieldsPrefix  = "fields."
+)
+
+// LogfmtFormatter implements the RecordFormatter interface for logfmt output
+//
+// In LogfmtAggregate mode one line is written per aggregated log:
+//
+//	type=request severity=WARN start_time=... end_time=... elapsed=45 lines=3 msg="slow query" method=GET path=/api/users
+//
+// msg is the message of the first line with the highest severity.
+//
+// In LogfmtExploded mode one line is written per entry; all lines of an aggregated log share
+// an aggregate_id and are numbered by seq:
+//
+//	time=... level=INFO msg="Request started" type=request aggregate_id=9f86d081884c7d65 seq=1 method=GET path=/api/users
+//	time=... level=WARN msg="slow query" type=request aggregate_id=9f86d081884c7d65 seq=2 method=GET path=/api/users table=users
+//
+// Keys are written in a fixed order followed by context fields and line fields in sorted order.
+// Nested maps are flattened with dotted keys ("request_headers.X-Request-Id"); slices and
+// other composite values are written as JSON. Context and line field keys that collide
+// with the keys written by the formatter are prefixed with "context." and "fields.".
+type LogfmtFormatter struct {
+	// Mode selects aggregate or exploded output
+	Mode LogfmtMode
+}
+
+// NewLogfmtFormatter creates a LogfmtFormatter that writes one line per aggregated log
+func NewLogfmtFormatter() *LogfmtFormatter {
+	return &LogfmtFormatter{Mode: LogfmtAggregate}
+}
+
+// NewExplodedLogfmtFormatter creates a LogfmtFormatter that writes one line per entry
+func NewExplodedLogfmtFormatter() *LogfmtFormatter {
+	return &LogfmtFormatter{Mode: LogfmtExploded}
+}
+
+// Format formats the log output as logfmt
+// In exploded mode the lines are separated by newlines; an output without entries
+// produces a single line with the context fields.
+func (f *LogfmtFormatter) Format(output *LogOutput) ([]byte, error) {
+	if f.Mode == LogfmtExploded {
+		return joinRecords(f.formatExploded(output)), nil
+	}
+	return f.formatAggregate(output), nil
+}
+
+// FormatRecords formats the log output as one record per logfmt line
+func (f *LogfmtFormatter) FormatRecords(output *LogOutput) ([]Record, error) {
+	if f.Mode == LogfmtExploded {
+		return f.formatExploded(output), nil
+	}
+	return []Record{{Level: output.Runtime.Severity, Data: f.formatAggregate(output)}}, nil
+}
+
+// formatAggregate writes a single line for the whole aggregated log
+func (f *LogfmtFormatter) formatAggregate(output *LogOutput) []byte {
+	var enc logfmtEncoder
+	enc.pair(LogfmtKeyType, output.Type)
+	enc.pair(LogfmtKeySeverity, output.Runtime.Severity)
+	enc.pair(LogfmtKeyStartTime, output.Runtime.StartTime)
+	enc.pair(LogfmtKeyEndTime, output.Runtime.EndTime)
+	enc.pair(LogfmtKeyElapsed, output.Runtime.Elapsed)
+	enc.pair(LogfmtKeyLines, len(output.Runtime.Lines))
+	if entry := mostSevereEntry(output.Runtime.Lines); entry != nil {
+		enc.pair(LogfmtKeyMessage, entry.Message)
+	}
+	if output.Runtime.Truncated {
+		enc.pair(LogfmtKeyTruncated, true)
+		enc.pair(LogfmtKeyDropped, output.Runtime.DroppedLines)
+	}
+
+	reserved := map[string]bool{
+		LogfmtKeyType: true, LogfmtKeySeverity: true, LogfmtKeyStartTime: true, LogfmtKeyEndTime: true,
+		LogfmtKeyElapsed: true, LogfmtKeyLines: true, LogfmtKeyMessage: true,
+		LogfmtKeyTruncated: true, LogfmtKeyDropped: true,
+	}
+	enc.fields(output.Context, reserved, logfmtContextPrefix)
+	return enc.buf.Bytes()
+}
+
+// formatExploded writes one line per entry with the context fields repeated
+func (f *LogfmtFormatter) formatExploded(output *LogOutput) []Record {
+	aggregateID := newAggregateID()
+	reserved := map[string]bool{
+		LogfmtKeyTime: true, LogfmtKeyLevel: true, LogfmtKeyMessage: true, LogfmtKeyType: true,
+		LogfmtKeyAggregateID: true, LogfmtKeySeq: true, LogfmtKeySpanID: true,
+		LogfmtKeyFunc: true, LogfmtKeySource: true, LogfmtKeyTruncated: true,
+	}
+
+	if len(output.Runtime.Lines) == 0 {
+		var enc logfmtEncoder
+		enc.pair(LogfmtKeyTime, output.Runtime.StartTime)
+		enc.pair(LogfmtKeyLevel, output.Runtime.Severity)
+		enc.pair(LogfmtKeyType, output.Type)
+		enc.pair(LogfmtKeyAggregateID, aggregateID)
+		enc.fields(output.Context, reserved, logfmtContextPrefix)
+		return []Record{{Level: output.Runtime.Severity, Data: enc.buf.Bytes()}}
+	}
+
+	// Line fields must not collide with reserved keys or context fields
+	lineReserved := make(map[string]bool, len(reserved)+len(output.Context))
+	for k := range reserved {
+		lineReserved[k] = true
+	}
+	for k := range output.Context {
+		lineReserved[k] = true
+	}
+
+	records := make([]Record, 0, len(output.Runtime.Lines))
+	for i, entry := range output.Runtime.Lines {
+		var enc logfmtEncoder
+		enc.pair(LogfmtKeyTime, entry.Timestamp.Format(time.RFC3339Nano))
+		enc.pair(LogfmtKeyLevel, entry.Level)
+		enc.pair(LogfmtKeyMessage, entry.Message)
+		enc.pair(LogfmtKeyType, output.Type)
+		enc.pair(LogfmtKeyAggregateID, aggregateID)
+		enc.pair(LogfmtKeySeq, i+1)
+		if entry.SpanID != "" {
+			enc.pair(LogfmtKeySpanID, entry.SpanID)
+		}
+		if entry.Funcname != "" {
+			enc.pair(LogfmtKeyFunc, entry.Funcname)
+		}
+		if entry.Filename != "" {
+			enc.pair(LogfmtKeySource, fmt.Sprintf("%s:%d", entry.Filename, entry.Fileline))
+		}
+		if entry.Truncated {
+			enc.pair(LogfmtKeyTruncated, true)
+		}
+		enc.fields(output.Context, reserved, logfmtContextPrefix)
+		enc.fields(entry.Fields, lineReserved, logfmtFieldsPrefix)
+
+		records = append(records, Record{Level: entry.Level, Data: enc.buf.Bytes()})
+	}
+	return records
+}
+
+// mostSevereEntry returns the first entry with the highest severity, or nil
+func mostSevereEntry(entries []*LogEntry) *LogEntry {
+	var found *LogEntry
+	highest := -1
+	for _, entry := range entries {
+		if rank := levelRank(entry.Level); rank > highest {
+			found = entry
+			highest = rank
+		}
+	}
+	return found
+}
+
+// levelRank orders level names from DEBUG to CRITICAL
+func levelRank(level string) int {
+	switch level {
+	case "DEBUG":
+		return 0
+	case "INFO":
+		return 1
+	case "WARN":
+		return 2
+	case "ERROR":
+		return 3
+	case "CRITICAL":
+		return 4
+	default:
+		return 1
+	}
+}
+
+// newAggregateID generates a random identifier shared by the documents of an exploded log
+func newAggregateID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// logfmtEncoder builds a single logfmt line
+type logfmtEncoder struct {
+	buf bytes.Buffer
+}
+
+// pair writes key=value, separated from the previous pair by a space
+func (e *logfmtEncoder) pair(key string, value interface{}) {
+	if e.buf.Len() > 0 {
+		e.buf.WriteByte(' ')
+	}
+	e.buf.WriteString(logfmtKey(key))
+	e.buf.WriteByte('=')
+	e.buf.WriteString(logfmtValue(value))
+}
+
+// fields writes the fields in sorted key order, flattening nested maps
+// Keys in reserved are written with the prefix.
+func (e *logfmtEncoder) fields(fields map[string]interface{}, reserved map[string]bool, prefix string) {
+	for _, key := range sortedKeys(fields) {
+		name := key
+		if reserved[key] {
+			name = prefix + key
+		}
+		e.value(name, fields[key])
+	}
+}
+
+// value writes a field, flattening nested maps into dotted keys
+func (e *logfmtEncoder) value(key string, value interface{}) {
+	if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+		for _, k := range sortedKeys(nested) {
+			e.value(key+"."+k, nested[k])
+		}
+		return
+	}
+	e.pair(key, value)
+}
+
+// logfmtKey replaces characters that are not allowed in logfmt keys with underscores
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue formats a value, quoting it when needed
+func logfmtValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quoteLogfmt(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error:
+		return quoteLogfmt(v.Error())
+	case fmt.Stringer:
+		return quoteLogfmt(v.String())
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return quoteLogfmt(fmt.Sprint(v))
+		}
+		return quoteLogfmt(string(data))
+	}
+}
+
+// quoteLogfmt quotes s if it is empty or contains spaces, '=', '"', '\' or control characters
+func quoteLogfmt(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,283 @@
+package formatter
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogfmtFormatter_Aggregate(t *testing.T) {
+	formatter := NewLogfmtFormatter()
+
+	result, err := formatter.Format(testLogOutput())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := `type=request severity=WARN start_time=2024-01-02T10:00:00.1Z end_time=2024-01-02T10:00:00.1451234Z ` +
+		`elapsed=45 lines=2 msg="slow query" headers.Accept=*/* headers.X-Request-Id=abc method=GET path=/api/users ` +
+		`status_code=200 tags="[\"a\",\"b\"]" tenant=acme trace_id=4bf92f3577b34da6a3ce929d0e0e4736`
+	if string(result) != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", result, expected)
+	}
+}
+
+func TestLogfmtFormatter_AggregateWithoutLines(t *testing.T) {
+	formatter := NewLogfmtFormatter()
+	output := &LogOutput{Type: "request", Runtime: RuntimeInfo{Severity: "DEBUG"}}
+
+	result, err := formatter.Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := `type=request severity=DEBUG start_time="" end_time="" elapsed=0 lines=0`
+	if string(result) != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", result, expected)
+	}
+}
+
+func TestLogfmtFormatter_Exploded(t *testing.T) {
+	formatter := NewExplodedLogfmtFormatter()
+
+	result, err := formatter.Format(testLogOutput())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	lines := strings.Split(string(result), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d:\n%s", len(lines), result)
+	}
+
+	idPattern := regexp.MustCompile(`aggregate_id=([0-9a-f]{16})`)
+	ids := make([]string, len(lines))
+	for i, line := range lines {
+		match := idPattern.FindStringSubmatch(line)
+		if match == nil {
+			t.Fatalf("Line %d has no aggregate_id: %s", i, line)
+		}
+		ids[i] = match[1]
+	}
+	if ids[0] != ids[1] {
+		t.Errorf("Expected a shared aggregate_id, got %q and %q", ids[0], ids[1])
+	}
+
+	id := ids[0]
+	context := ` headers.Accept=*/* headers.X-Request-Id=abc method=GET path=/api/users status_code=200` +
+		` tags="[\"a\",\"b\"]" tenant=acme trace_id=4bf92f3577b34da6a3ce929d0e0e4736`
+	expected := []string{
+		`time=2024-01-02T10:00:00.123Z level=INFO msg="Request started" type=request aggregate_id=` + id +
+			` seq=1` + context,
+		`time=2024-01-02T10:00:00.15Z level=WARN msg="slow query" type=request aggregate_id=` + id +
+			` seq=2 span_id=00f067aa0ba902b7 func=main.query source=db.go:42` + context +
+			` query="SELECT 1" rows=3 table=users`,
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Unexpected line %d:\n%s\nexpected:\n%s", i, lines[i], expected[i])
+		}
+	}
+
+	// Every flush gets a new aggregate_id
+	again, _ := formatter.Format(testLogOutput())
+	if strings.Contains(string(again), id) {
+		t.Errorf("Expected a new aggregate_id for another output, got %s", again)
+	}
+}
+
+func TestLogfmtFormatter_ExplodedWithoutLines(t *testing.T) {
+	formatter := NewExplodedLogfmtFormatter()
+	output := &LogOutput{
+		Type:    "request",
+		Context: map[string]interface{}{"path": "/"},
+		Runtime: RuntimeInfo{Severity: "DEBUG", StartTime: "2024-01-02T10:00:00+00:00"},
+	}
+
+	result, err := formatter.Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	if strings.Contains(string(result), "\n") {
+		t.Errorf("Expected a single line, got:\n%s", result)
+	}
+	if !regexp.MustCompile(`^time=2024-01-02T10:00:00\+00:00 level=DEBUG type=request aggregate_id=[0-9a-f]{16} path=/$`).Match(result) {
+		t.Errorf("Unexpected output: %s", result)
+	}
+}
+
+func TestLogfmtFormatter_KeyCollisions(t *testing.T) {
+	output := &LogOutput{
+		Type:    "request",
+		Context: map[string]interface{}{"type": "ctx", "path": "/"},
+		Runtime: RuntimeInfo{
+			Severity: "INFO",
+			Lines: []*LogEntry{{
+				Timestamp: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+				Level:     "INFO",
+				Message:   "hello",
+				Fields:    map[string]interface{}{"msg": "field", "path": "/other", "seq": 9},
+			}},
+		},
+	}
+
+	result, err := NewLogfmtFormatter().Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.HasPrefix(string(result), "type=request ") || !strings.Contains(string(result), " context.type=ctx") {
+		t.Errorf("Expected the colliding context key to be prefixed, got %s", result)
+	}
+
+	result, err = NewExplodedLogfmtFormatter().Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	for _, want := range []string{" msg=hello ", " context.type=ctx", " path=/ ", " fields.msg=field", " fields.path=/other", " fields.seq=9", " seq=1 "} {
+		if !strings.Contains(string(result), want) {
+			t.Errorf("Expected %q in %s", want, result)
+		}
+	}
+}
+
+func TestLogfmtFormatter_Escaping(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"plain", "abc", "abc"},
+		{"empty", "", `""`},
+		{"space", "a b", `"a b"`},
+		{"equals", "a=b", `"a=b"`},
+		{"quote", `say "hi"`, `"say \"hi\""`},
+		{"backslash", `C:\tmp`, `"C:\\tmp"`},
+		{"newline", "line1\nline2", `"line1\nline2"`},
+		{"tab", "a\tb", `"a\tb"`},
+		{"control", "a\x00b", `"a\x00b"`},
+		{"unicode", "こんにちは", "こんにちは"},
+		{"nil", nil, "null"},
+		{"bool", true, "true"},
+		{"int", 42, "42"},
+		{"float", 1.5, "1.5"},
+		{"error", errors.New("not found"), `"not found"`},
+		{"time", time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), "2024-01-02T10:00:00Z"},
+		{"slice", []string{"a", "b"}, `"[\"a\",\"b\"]"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logfmtValue(tt.value); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLogfmtFormatter_KeySanitizing(t *testing.T) {
+	output := &LogOutput{
+		Type:    "request",
+		Context: map[string]interface{}{"user id": 1, "a=b": 2, `q"k`: 3, "": 4},
+		Runtime: RuntimeInfo{Severity: "INFO"},
+	}
+
+	result, err := NewLogfmtFormatter().Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := `type=request severity=INFO start_time="" end_time="" elapsed=0 lines=0 _=4 a_b=2 q_k=3 user_id=1`
+	if string(result) != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", result, expected)
+	}
+}
+
+func TestLogfmtFormatter_DeterministicOrder(t *testing.T) {
+	output := &LogOutput{
+		Type:    "request",
+		Context: map[string]interface{}{},
+		Runtime: RuntimeInfo{Severity: "INFO"},
+	}
+	for _, key := range []string{"zeta", "alpha", "mu", "beta", "omega", "gamma", "delta", "kappa"} {
+		output.Context[key] = key
+	}
+
+	first, _ := NewLogfmtFormatter().Format(output)
+	for i := 0; i < 20; i++ {
+		result, _ := NewLogfmtFormatter().Format(output)
+		if string(result) != string(first) {
+			t.Fatalf("Output changed between calls:\n%s\n%s", first, result)
+		}
+	}
+	if !strings.HasSuffix(string(first), "alpha=alpha beta=beta delta=delta gamma=gamma kappa=kappa mu=mu omega=omega zeta=zeta") {
+		t.Errorf("Expected context keys in sorted order, got %s", first)
+	}
+}
+
+func TestLogfmtFormatter_MostSevereMessage(t *testing.T) {
+	output := &LogOutput{
+		Type: "request",
+		Runtime: RuntimeInfo{
+			Severity: "ERROR",
+			Lines: []*LogEntry{
+				{Level: "INFO", Message: "first"},
+				{Level: "ERROR", Message: "first error"},
+				{Level: "WARN", Message: "warning"},
+				{Level: "ERROR", Message: "second error"},
+			},
+		},
+	}
+
+	result, err := NewLogfmtFormatter().Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(string(result), ` lines=4 msg="first error"`) {
+		t.Errorf("Expected the first most severe message, got %s", result)
+	}
+}
+
+func TestLogfmtFormatter_FormatRecords(t *testing.T) {
+	records, err := NewExplodedLogfmtFormatter().FormatRecords(testLogOutput())
+	if err != nil {
+		t.Fatalf("FormatRecords failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Level != "INFO" || records[1].Level != "WARN" {
+		t.Fatalf("Expected INFO and WARN records, got %v", records)
+	}
+
+	records, err = NewLogfmtFormatter().FormatRecords(testLogOutput())
+	if err != nil {
+		t.Fatalf("FormatRecords failed: %v", err)
+	}
+	if len(records) != 1 || records[0].Level != "WARN" {
+		t.Fatalf("Expected a single WARN record, got %v", records)
+	}
+}
+
+func TestLogfmtFormatter_TruncationMarkers(t *testing.T) {
+	output := testLogOutput()
+	output.Runtime.Truncated = true
+	output.Runtime.DroppedLines = 812
+	output.Runtime.Lines[0].Truncated = true
+
+	result, err := NewLogfmtFormatter().Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(string(result), ` msg="slow query" truncated=true dropped_lines=812 `) {
+		t.Errorf("Expected the truncation markers, got %s", result)
+	}
+
+	result, err = NewExplodedLogfmtFormatter().Format(output)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	lines := strings.Split(string(result), "\n")
+	if !strings.Contains(lines[0], " truncated=true ") || strings.Contains(lines[1], "truncated") {
+		t.Errorf("Expected only the truncated line to be marked, got:\n%s", result)
+	}
+}
@@ -42,6 +42,32 @@
 //	    http.ListenAndServe(":8080", nil)
 //	}
 //
+// # Options
+//
+// NewLoggingMiddleware builds the same middleware with options:
+//
+//	middleware := http_middleware.NewLoggingMiddleware(
+//	    http_middleware.WithSkipPaths("/healthz", "/readyz"),          // Do not log health checks
+//	    http_middleware.WithSkipMethods(http.MethodOptions),          // Do not log CORS preflights
+//	    http_middleware.WithRequestHeaders("X-Request-Id"),           // Capture request headers
+//	    http_middleware.WithResponseHeaders("X-Cache"),               // Capture response headers
+//	    http_middleware.WithFieldName(http_middleware.FieldStatusCode, "status"),
+//	    http_middleware.WithoutFields(http_middleware.FieldQuery),     // Omit built-in fields
+//	    http_middleware.WithRequestLines(false),                      // No "Request started/completed" lines
+//	    http_middleware.WithFieldsFunc(func(r *http.Request) map[string]interface{} {
+//	        return map[string]interface{}{"tenant": r.Header.Get("X-Tenant")}
+//	    }),
+//	    http_middleware.WithRepanic(false),                           // Write a 500 instead of re-panicking
+//	    http_middleware.WithLoggerOptions(logger.WithContextLogType("access")),
+//	)
+//	handler := middleware(mux)
+//
+// WithMiddleware and WithOutputMiddleware add middleware that applies only to the request
+// loggers of this middleware; with WithGlobalMiddleware(false) the global chains are ignored.
+//
+// Skipped requests still carry a logger in their context, so handlers can log
+// unconditionally; nothing is written for them.
+//
 // # Automatic Context Information
 //
 // The middleware automatically adds the following information to the logging context:
@@ -53,8 +79,25 @@
 //   - user_agent: User-Agent header
 //   - remote_addr: Client's remote address
 //   - host: Host header
+//   - proto: Protocol version, such as "HTTP/1.1" or "HTTP/2.0"
+//   - tls_version: Negotiated TLS version, such as "TLS 1.3" (only for TLS connections)
+//   - content_type: Request Content-Type header (only when set)
+//   - trace_id: W3C trace ID, taken from the traceparent header or newly generated
+//   - span_id: Span ID of this request within the trace
+//   - trace_flags: Trace flags as two hex characters ("01" when sampled)
+//   - parent_span_id: Span ID of the caller (only when a valid traceparent header was received)
 //   - status_code: HTTP response status code (added after response)
 //   - duration_ms: Request processing duration in milliseconds (added after response)
+//   - request_bytes: Number of request body bytes read by the handler (added after response)
+//   - response_bytes: Number of response body bytes written by the handler (added after response)
+//   - response_content_type: Response Content-Type header (added after response, only when set)
+//
+// The response writer passed to handlers implements exactly the optional interfaces of the
+// underlying writer: http.Flusher, http.Hijacker, io.ReaderFrom and http.Pusher are
+// available only when the original writer supports them, so a type assertion such as
+// w.(http.Hijacker) fails on HTTP/2 as without the middleware. http.NewResponseController
+// can reach the original writer through Unwrap.
+// A hijacked connection is logged with status code 101 unless a status was written before.
 //
 // # Request Lifecycle
 //
@@ -69,6 +112,39 @@
 //  7. Log "Request completed" message
 //  8. Flush all accumulated logs for the request
 //
+// # Status-Based Severity
+//
+// By default runtime.severity is the highest level of the logged lines. With
+// WithStatusSeverity(DefaultStatusSeverity) it is also raised from the response status code:
+// 4xx responses are at least WARN and 5xx responses at least ERROR. WithStatusSeverity accepts
+// any mapping; WithStatusSeverity(nil) disables it again.
+// Handlers can attach errors to the request log with logger.RecordError.
+//
+// # Panic Recovery
+//
+// If a handler panics, the middleware logs a CRITICAL line with the panic value and
+// the stack trace (see logger.LogPanic), sets status_code to 500 and flushes the request
+// logs. By default the panic is then re-raised, so net/http and outer middlewares see it
+// as before; with WithRepanic(false) a 500 response is written instead, unless the handler
+// already started the response. http.ErrAbortHandler is always re-raised and not logged
+// as CRITICAL.
+//
+// # Trace Context Propagation
+//
+// The middleware follows the W3C Trace Context specification. An incoming traceparent
+// header is continued with a new span ID; otherwise a new trace is started. The trace
+// context is stored in the request context and can be read with logger.TraceIDFromContext,
+// logger.SpanIDFromContext and logger.TraceContextFromContext.
+//
+// To propagate the trace to downstream services, use TraceTransport for outgoing requests:
+//
+//	client := &http.Client{Transport: http_middleware.NewTraceTransport(nil)}
+//	req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://backend/api", nil)
+//	resp, err := client.Do(req)
+//
+// When a logspan span is active in the context (see logger.StartSpan), its ID is sent as
+// the parent ID, so the downstream request is attached to that span.
+//
 // # Integration with Custom Handlers
 //
 // The middleware works seamlessly with any HTTP handler:
@@ -124,8 +200,16 @@
 //	    "user_agent": "Mozilla/5.0...",
 //	    "remote_addr": "127.0.0.1:54321",
 //	    "host": "localhost:8080",
+//	    "proto": "HTTP/1.1",
+//	    "content_type": "application/json",
+//	    "trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+//	    "span_id": "53995c3f42cd8ad8",
+//	    "trace_flags": "01",
 //	    "status_code": 201,
 //	    "duration_ms": 45,
+//	    "request_bytes": 58,
+//	    "response_bytes": 112,
+//	    "response_content_type": "application/json",
 //	    "user_id": "user-123",
 //	    "operation": "create_user"
 //	  },
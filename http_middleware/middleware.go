@@ -1,6 +1,8 @@
 package http_middleware
 
 import (
+	"context"
+	"crypto/tls"
 	"net/http"
 	"time"
 
@@ -8,67 +10,178 @@ import (
 )
 
 // LoggingMiddleware creates an HTTP middleware that automatically sets up logging context
-// for each request and collects basic HTTP request information
+// for each request, continues or starts a W3C trace, and collects basic HTTP request information
+// It is equivalent to NewLoggingMiddleware() with default options
 func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Create a new context logger for this request
-		contextLogger := logger.NewContextLogger()
-
-		// Add basic HTTP request information to the context
-		contextLogger.AddContextValues(map[string]interface{}{
-			"method":      r.Method,
-			"url":         r.URL.String(),
-			"path":        r.URL.Path,
-			"query":       r.URL.RawQuery,
-			"user_agent":  r.UserAgent(),
-			"remote_addr": r.RemoteAddr,
-			"host":        r.Host,
+	return NewLoggingMiddleware()(next)
+}
+
+// NewLoggingMiddleware creates an HTTP middleware like LoggingMiddleware, configured with options
+//
+// Usage:
+//
+//	middleware := http_middleware.NewLoggingMiddleware(
+//	    http_middleware.WithSkipPaths("/healthz"),
+//	    http_middleware.WithRequestHeaders("X-Request-Id"),
+//	    http_middleware.WithFieldName(http_middleware.FieldStatusCode, "status"),
+//	)
+//	handler := middleware(mux)
+func NewLoggingMiddleware(options ...Option) func(http.Handler) http.Handler {
+	cfg := newConfig(options)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.shouldSkip(r) {
+				// Attach a logger without output or sink, which discards lines instead of keeping
+				// them, so handlers can log without checks
+				contextLogger := logger.NewContextLogger()
+				contextLogger.SetOutput(nil)
+				contextLogger.SetSink(nil)
+				next.ServeHTTP(w, r.WithContext(logger.WithLogger(r.Context(), contextLogger)))
+				return
+			}
+
+			cfg.serveHTTP(next, w, r)
 		})
+	}
+}
 
-		// Create a response writer wrapper to capture response information
-		wrappedWriter := &responseWriter{
-			ResponseWriter: w,
-			statusCode:     http.StatusOK, // Default to 200
+// serveHTTP logs a single request handled by next
+func (c *config) serveHTTP(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	// Create a new context logger for this request
+	contextLogger := logger.NewContextLoggerWithOptions(c.loggerOptions...)
+
+	// Continue the caller's trace or start a new one
+	traceContext := traceContextFromRequest(r)
+
+	// Add basic HTTP request information to the context
+	fields := make(map[string]interface{})
+	c.setField(fields, FieldMethod, r.Method)
+	c.setField(fields, FieldURL, r.URL.String())
+	c.setField(fields, FieldPath, r.URL.Path)
+	c.setField(fields, FieldQuery, r.URL.RawQuery)
+	c.setField(fields, FieldUserAgent, r.UserAgent())
+	c.setField(fields, FieldRemoteAddr, r.RemoteAddr)
+	c.setField(fields, FieldHost, r.Host)
+	c.setField(fields, FieldProto, r.Proto)
+	if r.TLS != nil {
+		c.setField(fields, FieldTLSVersion, tls.VersionName(r.TLS.Version))
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		c.setField(fields, FieldContentType, contentType)
+	}
+	c.setField(fields, FieldTraceID, traceContext.TraceID)
+	c.setField(fields, FieldSpanID, traceContext.SpanID)
+	c.setField(fields, FieldTraceFlags, traceContext.FlagsString())
+	if traceContext.ParentSpanID != "" {
+		c.setField(fields, FieldParentSpanID, traceContext.ParentSpanID)
+	}
+	if len(c.requestHeaders) > 0 {
+		c.setField(fields, FieldRequestHeaders, captureHeaders(r.Header, c.requestHeaders))
+	}
+	if c.fieldsFunc != nil {
+		for k, v := range c.fieldsFunc(r) {
+			fields[k] = v
 		}
+	}
+	contextLogger.AddContextValues(fields)
 
-		// Add the logger to the request context
-		ctx := logger.WithLogger(r.Context(), contextLogger)
-		r = r.WithContext(ctx)
+	// Create a response writer wrapper to capture response information
+	wrappedWriter := &responseWriter{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK, // Default to 200
+	}
 
-		// Log the start of the request
-		logger.Infof(ctx, "Request started")
+	// Add the logger and the trace context to the request context
+	ctx := logger.WithLogger(r.Context(), contextLogger)
+	ctx = logger.WithTraceContext(ctx, traceContext)
+	r = r.WithContext(ctx)
 
-		// Record start time for duration calculation
-		startTime := time.Now()
+	// Count the request body bytes read by the handler
+	requestBody := &countingReadCloser{ReadCloser: r.Body}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = requestBody
+	}
 
-		// Call the next handler
-		next.ServeHTTP(wrappedWriter, r)
+	// Log the start of the request
+	if c.requestLines {
+		logger.Infof(ctx, "Request started")
+	}
 
-		// Calculate request duration
-		duration := time.Since(startTime)
+	// Record start time for duration calculation
+	startTime := time.Now()
 
-		// Add response information to the context
-		contextLogger.AddContextValues(map[string]interface{}{
-			"status_code": wrappedWriter.statusCode,
-			"duration_ms": duration.Milliseconds(),
-		})
+	// Log and flush the request even when the handler panics
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+		c.recoverPanic(ctx, value, contextLogger, wrappedWriter, requestBody, startTime)
+	}()
 
-		// Log the completion of the request
-		logger.Infof(ctx, "Request completed")
+	// Call the next handler
+	next.ServeHTTP(wrappedWriter.wrap(), r)
 
-		// Flush the accumulated logs
-		logger.FlushContext(ctx)
-	})
+	c.finishRequest(ctx, contextLogger, wrappedWriter, requestBody, startTime)
 }
 
-// responseWriter wraps http.ResponseWriter to capture response information
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
+// recoverPanic logs a panic raised by the handler, flushes the request logs and then
+// re-panics or writes a 500 response depending on the configuration
+// http.ErrAbortHandler is always re-panicked without logging a CRITICAL line, as it is
+// the documented way to abort a response.
+func (c *config) recoverPanic(ctx context.Context, value interface{}, contextLogger *logger.ContextLogger,
+	w *responseWriter, requestBody *countingReadCloser, startTime time.Time) {
+	abort := value == http.ErrAbortHandler
+	if !abort {
+		logger.LogPanic(ctx, value)
+	}
+
+	repanic := c.repanic || abort
+	if !repanic && !w.wroteHeader && !w.hijacked {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+
+	// Report a server error even if the handler already wrote another status
+	w.statusCode = http.StatusInternalServerError
+	c.finishRequest(ctx, contextLogger, w, requestBody, startTime)
+
+	if repanic {
+		panic(value)
+	}
 }
 
-// WriteHeader captures the status code
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
+// finishRequest adds the response information to the context, logs the completion
+// of the request and flushes the accumulated logs
+func (c *config) finishRequest(ctx context.Context, contextLogger *logger.ContextLogger,
+	wrappedWriter *responseWriter, requestBody *countingReadCloser, startTime time.Time) {
+	// Calculate request duration
+	duration := time.Since(startTime)
+
+	// Add response information to the context
+	responseFields := make(map[string]interface{})
+	c.setField(responseFields, FieldStatusCode, wrappedWriter.statusCode)
+	c.setField(responseFields, FieldDurationMS, duration.Milliseconds())
+	c.setField(responseFields, FieldRequestBytes, requestBody.bytes)
+	c.setField(responseFields, FieldResponseBytes, wrappedWriter.bytes)
+	if contentType := wrappedWriter.Header().Get("Content-Type"); contentType != "" && !wrappedWriter.hijacked {
+		c.setField(responseFields, FieldResponseContentType, contentType)
+	}
+	if len(c.responseHeaders) > 0 {
+		c.setField(responseFields, FieldResponseHeaders, captureHeaders(wrappedWriter.Header(), c.responseHeaders))
+	}
+	contextLogger.AddContextValues(responseFields)
+
+	// Raise the aggregate severity for error responses
+	if c.statusSeverity != nil {
+		contextLogger.RaiseSeverity(c.statusSeverity(wrappedWriter.statusCode))
+	}
+
+	// Log the completion of the request
+	if c.requestLines {
+		logger.Infof(ctx, "Request completed")
+	}
+
+	// Flush the accumulated logs
+	logger.FlushContext(ctx)
 }
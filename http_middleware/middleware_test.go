@@ -176,3 +176,120 @@ func TestResponseWriterWrapper(t *testing.T) {
 		t.Errorf("Expected status_code to be 404, got %v", context["status_code"])
 	}
 }
+
+// serveWithPanic serves a request with a handler that panics and returns the log output,
+// the response recorder and the value recovered outside the middleware
+func serveWithPanic(panicValue interface{}, options ...Option) (string, *httptest.ResponseRecorder, interface{}) {
+	var logOutput bytes.Buffer
+	wrapped := NewLoggingMiddleware(options...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).SetOutput(&logOutput)
+		logger.Infof(r.Context(), "before panic")
+		panic(panicValue)
+	}))
+
+	recorder := httptest.NewRecorder()
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		wrapped.ServeHTTP(recorder, httptest.NewRequest("GET", "/boom", nil))
+	}()
+	return logOutput.String(), recorder, recovered
+}
+
+func TestLoggingMiddleware_PanicRepanics(t *testing.T) {
+	output, _, recovered := serveWithPanic("something went wrong")
+
+	if recovered != "something went wrong" {
+		t.Errorf("Expected the panic to be re-raised, got %v", recovered)
+	}
+
+	var logData struct {
+		Context map[string]interface{} `json:"context"`
+		Runtime struct {
+			Severity string `json:"severity"`
+			Lines    []struct {
+				Level   string                 `json:"level"`
+				Message string                 `json:"message"`
+				Fields  map[string]interface{} `json:"fields"`
+			} `json:"lines"`
+		} `json:"runtime"`
+	}
+	if err := json.Unmarshal([]byte(output), &logData); err != nil {
+		t.Fatalf("Expected the request to be flushed, got %v, output: %s", err, output)
+	}
+
+	if logData.Context["status_code"] != float64(500) {
+		t.Errorf("Expected status_code 500, got %v", logData.Context["status_code"])
+	}
+	if logData.Runtime.Severity != "CRITICAL" {
+		t.Errorf("Expected CRITICAL severity, got %s", logData.Runtime.Severity)
+	}
+
+	var panicLine bool
+	for _, line := range logData.Runtime.Lines {
+		if line.Level != "CRITICAL" {
+			continue
+		}
+		panicLine = true
+		if line.Fields["panic"] != "something went wrong" {
+			t.Errorf("Expected panic field, got %v", line.Fields["panic"])
+		}
+		stack, ok := line.Fields["stack"].([]interface{})
+		if !ok || len(stack) == 0 {
+			t.Fatalf("Expected stack frames, got %v", line.Fields["stack"])
+		}
+		top := stack[0].(map[string]interface{})
+		if !strings.Contains(top["function"].(string), "serveWithPanic") {
+			t.Errorf("Expected the first frame to be the panicking handler, got %v", top["function"])
+		}
+	}
+	if !panicLine {
+		t.Errorf("Expected a CRITICAL panic line, got %s", output)
+	}
+	if !strings.Contains(output, "before panic") {
+		t.Error("Expected lines logged before the panic to be kept")
+	}
+}
+
+func TestLoggingMiddleware_PanicWrites500(t *testing.T) {
+	output, recorder, recovered := serveWithPanic("boom", WithRepanic(false))
+
+	if recovered != nil {
+		t.Errorf("Expected the panic to be swallowed, got %v", recovered)
+	}
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 response, got %d", recorder.Code)
+	}
+	if !strings.Contains(output, `"status_code":500`) {
+		t.Errorf("Expected status_code 500 in the log, got %s", output)
+	}
+}
+
+func TestLoggingMiddleware_PanicAbortHandler(t *testing.T) {
+	output, _, recovered := serveWithPanic(http.ErrAbortHandler, WithRepanic(false))
+
+	if recovered != http.ErrAbortHandler {
+		t.Errorf("Expected http.ErrAbortHandler to be re-raised, got %v", recovered)
+	}
+	if strings.Contains(output, "CRITICAL") {
+		t.Errorf("Expected no CRITICAL line for an aborted handler, got %s", output)
+	}
+	if !strings.Contains(output, `"status_code":500`) {
+		t.Errorf("Expected the aborted request to be flushed, got %s", output)
+	}
+}
+
+func TestLoggingMiddleware_UsesGlobalOutput(t *testing.T) {
+	var buf bytes.Buffer
+	logger.Init(logger.WithOutput(&buf))
+	defer logger.Init()
+
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Infof(r.Context(), "handled")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !strings.Contains(buf.String(), "handled") {
+		t.Errorf("Expected the request log on the output set with Init, got %q", buf.String())
+	}
+}
@@ -0,0 +1,294 @@
+package http_middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zentooo/logspan/logger"
+)
+
+// Built-in context field names written by the logging middleware
+// Use WithFieldName to rename them and WithoutFields to omit them
+const (
+	FieldMethod       = "method"
+	FieldURL          = "url"
+	FieldPath         = "path"
+	FieldQuery        = "query"
+	FieldUserAgent    = "user_agent"
+	FieldRemoteAddr   = "remote_addr"
+	FieldHost         = "host"
+	FieldProto        = "proto"
+	FieldTLSVersion   = "tls_version"  // Only present for TLS connections
+	FieldContentType  = "content_type" // Request Content-Type, only present when set
+	FieldTraceID      = "trace_id"
+	FieldSpanID       = "span_id"
+	FieldTraceFlags   = "trace_flags"
+	FieldParentSpanID = "parent_span_id"
+	FieldStatusCode   = "status_code"
+	FieldDurationMS   = "duration_ms"
+
+	// FieldRequestBytes is the number of request body bytes read by the handler
+	FieldRequestBytes = "request_bytes"
+
+	// FieldResponseBytes is the number of response body bytes written by the handler
+	FieldResponseBytes = "response_bytes"
+
+	// FieldResponseContentType is the response Content-Type, only present when set
+	FieldResponseContentType = "response_content_type"
+
+	// FieldRequestHeaders holds the headers captured with WithRequestHeaders
+	FieldRequestHeaders = "request_headers"
+
+	// FieldResponseHeaders holds the headers captured with WithResponseHeaders
+	FieldResponseHeaders = "response_headers"
+)
+
+// config holds the configuration of the logging middleware (internal use)
+type config struct {
+	// skipPaths are request paths that are not logged
+	skipPaths map[string]bool
+
+	// skipPathPrefixes are request path prefixes that are not logged
+	skipPathPrefixes []string
+
+	// skipMethods are request methods that are not logged
+	skipMethods map[string]bool
+
+	// skipper decides whether a request is logged, in addition to the lists above
+	skipper func(*http.Request) bool
+
+	// requestHeaders are the request headers captured into the context
+	requestHeaders []string
+
+	// responseHeaders are the response headers captured into the context
+	responseHeaders []string
+
+	// fieldNames maps built-in field names to their output names; an empty name omits the field
+	fieldNames map[string]string
+
+	// requestLines enables the "Request started" and "Request completed" lines
+	requestLines bool
+
+	// fieldsFunc returns custom context fields for the request
+	fieldsFunc func(*http.Request) map[string]interface{}
+
+	// statusSeverity maps the response status code to the minimum aggregate severity
+	statusSeverity func(statusCode int) logger.LogLevel
+
+	// repanic re-raises handler panics after logging instead of writing a 500 response
+	repanic bool
+
+	// loggerOptions configure the context logger of each request
+	loggerOptions []logger.ContextLoggerOption
+}
+
+// Option is a function that configures the logging middleware
+type Option func(*config)
+
+// WithSkipPaths disables logging for requests whose path exactly matches one of the paths
+// Useful for health checks and metrics endpoints
+func WithSkipPaths(paths ...string) Option {
+	return func(c *config) {
+		for _, path := range paths {
+			c.skipPaths[path] = true
+		}
+	}
+}
+
+// WithSkipPathPrefixes disables logging for requests whose path starts with one of the prefixes
+func WithSkipPathPrefixes(prefixes ...string) Option {
+	return func(c *config) {
+		c.skipPathPrefixes = append(c.skipPathPrefixes, prefixes...)
+	}
+}
+
+// WithSkipMethods disables logging for requests with one of the methods, such as OPTIONS
+func WithSkipMethods(methods ...string) Option {
+	return func(c *config) {
+		for _, method := range methods {
+			c.skipMethods[strings.ToUpper(method)] = true
+		}
+	}
+}
+
+// WithSkipper disables logging for requests for which the function returns true
+func WithSkipper(skipper func(*http.Request) bool) Option {
+	return func(c *config) {
+		c.skipper = skipper
+	}
+}
+
+// WithRequestHeaders captures the given request headers into the request_headers context field
+// Header names are matched case-insensitively; multiple values are joined with ", "
+func WithRequestHeaders(names ...string) Option {
+	return func(c *config) {
+		c.requestHeaders = append(c.requestHeaders, names...)
+	}
+}
+
+// WithResponseHeaders captures the given response headers into the response_headers context field
+// Header names are matched case-insensitively; multiple values are joined with ", "
+func WithResponseHeaders(names ...string) Option {
+	return func(c *config) {
+		c.responseHeaders = append(c.responseHeaders, names...)
+	}
+}
+
+// WithFieldName renames a built-in context field, such as FieldStatusCode
+// An empty name omits the field
+func WithFieldName(field, name string) Option {
+	return func(c *config) {
+		c.fieldNames[field] = name
+	}
+}
+
+// WithoutFields omits the given built-in context fields
+func WithoutFields(fields ...string) Option {
+	return func(c *config) {
+		for _, field := range fields {
+			c.fieldNames[field] = ""
+		}
+	}
+}
+
+// WithRequestLines enables or disables the "Request started" and "Request completed" lines
+func WithRequestLines(enabled bool) Option {
+	return func(c *config) {
+		c.requestLines = enabled
+	}
+}
+
+// WithFieldsFunc sets a function that returns custom context fields for each request
+// The fields are added after the built-in request fields and may override them
+func WithFieldsFunc(fn func(*http.Request) map[string]interface{}) Option {
+	return func(c *config) {
+		c.fieldsFunc = fn
+	}
+}
+
+// WithRepanic controls what happens after a handler panic has been logged and flushed
+// When enabled (the default), the panic is re-raised so that outer middlewares and
+// net/http handle it as before. When disabled, the panic is swallowed and a 500 response
+// is written, unless the handler already started the response.
+func WithRepanic(enabled bool) Option {
+	return func(c *config) {
+		c.repanic = enabled
+	}
+}
+
+// WithStatusSeverity sets the function that maps the response status code to the minimum
+// aggregate severity (runtime.severity) of the request log. Status-based severity is disabled
+// by default so that only logged lines count; pass DefaultStatusSeverity to raise 4xx responses
+// to WARN and 5xx responses to ERROR, and nil to disable it again.
+//
+// Usage:
+//
+//	http_middleware.WithStatusSeverity(http_middleware.DefaultStatusSeverity)
+//
+//	http_middleware.WithStatusSeverity(func(statusCode int) logger.LogLevel {
+//	    if statusCode == http.StatusNotFound {
+//	        return logger.InfoLevel
+//	    }
+//	    return http_middleware.DefaultStatusSeverity(statusCode)
+//	})
+func WithStatusSeverity(fn func(statusCode int) logger.LogLevel) Option {
+	return func(c *config) {
+		c.statusSeverity = fn
+	}
+}
+
+// WithLoggerOptions configures the context logger created for each request, for example its
+// output, formatter or log type, instead of the global configuration
+func WithLoggerOptions(options ...logger.ContextLoggerOption) Option {
+	return func(c *config) {
+		c.loggerOptions = append(c.loggerOptions, options...)
+	}
+}
+
+// WithMiddleware adds middleware that applies only to the request loggers of this
+// logging middleware, after the global middleware chain
+func WithMiddleware(middlewares ...logger.Middleware) Option {
+	return WithLoggerOptions(logger.WithContextMiddleware(middlewares...))
+}
+
+// WithOutputMiddleware adds output middleware that applies only to the request loggers of
+// this logging middleware, after the global output middleware chain
+func WithOutputMiddleware(middlewares ...logger.OutputMiddleware) Option {
+	return WithLoggerOptions(logger.WithContextOutputMiddleware(middlewares...))
+}
+
+// WithGlobalMiddleware enables or disables the global middleware chains for the request
+// loggers. When disabled, only the middleware added with WithMiddleware and WithOutputMiddleware
+// is applied. Enabled by default
+func WithGlobalMiddleware(enabled bool) Option {
+	return WithLoggerOptions(logger.WithContextGlobalMiddleware(enabled))
+}
+
+// DefaultStatusSeverity maps 5xx status codes to ERROR and 4xx status codes to WARN
+// Other status codes return DEBUG, which leaves the severity of the logged lines unchanged
+func DefaultStatusSeverity(statusCode int) logger.LogLevel {
+	switch {
+	case statusCode >= 500:
+		return logger.ErrorLevel
+	case statusCode >= 400:
+		return logger.WarnLevel
+	default:
+		return logger.DebugLevel
+	}
+}
+
+// defaultConfig returns a default configuration
+func defaultConfig() config {
+	return config{
+		skipPaths:    make(map[string]bool),
+		skipMethods:  make(map[string]bool),
+		fieldNames:   make(map[string]string),
+		requestLines: true,
+		repanic:      true,
+	}
+}
+
+// newConfig creates a configuration from the default settings and the given options
+func newConfig(options []Option) *config {
+	c := defaultConfig()
+	for _, option := range options {
+		option(&c)
+	}
+	return &c
+}
+
+// shouldSkip reports whether the request must not be logged
+func (c *config) shouldSkip(r *http.Request) bool {
+	if c.skipPaths[r.URL.Path] || c.skipMethods[r.Method] {
+		return true
+	}
+	for _, prefix := range c.skipPathPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return c.skipper != nil && c.skipper(r)
+}
+
+// setField stores a built-in field under its configured name, unless the field is omitted
+func (c *config) setField(fields map[string]interface{}, field string, value interface{}) {
+	name, renamed := c.fieldNames[field]
+	if !renamed {
+		name = field
+	}
+	if name == "" {
+		return
+	}
+	fields[name] = value
+}
+
+// captureHeaders returns the configured headers that are present, keyed by canonical name
+func captureHeaders(header http.Header, names []string) map[string]interface{} {
+	captured := make(map[string]interface{})
+	for _, name := range names {
+		if values := header.Values(name); len(values) > 0 {
+			captured[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+		}
+	}
+	return captured
+}
@@ -0,0 +1,281 @@
+package http_middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zentooo/logspan/formatter"
+	"github.com/zentooo/logspan/logger"
+)
+
+func TestNewLoggingMiddleware_SkipRequests(t *testing.T) {
+	options := []Option{
+		WithSkipPaths("/healthz"),
+		WithSkipPathPrefixes("/metrics/"),
+		WithSkipMethods("options"),
+		WithSkipper(func(r *http.Request) bool { return r.Header.Get("X-Internal") == "1" }),
+	}
+
+	internal := httptest.NewRequest("GET", "/api", nil)
+	internal.Header.Set("X-Internal", "1")
+
+	skipped := []*http.Request{
+		httptest.NewRequest("GET", "/healthz", nil),
+		httptest.NewRequest("GET", "/metrics/cpu", nil),
+		httptest.NewRequest("OPTIONS", "/api", nil),
+		internal,
+	}
+	for _, req := range skipped {
+		called := false
+		output := serveAndCapture(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			// Logging from a skipped request must be safe and silent
+			logger.Infof(r.Context(), "handler line")
+		}, req, options...)
+
+		if !called {
+			t.Errorf("%s %s: expected handler to be called", req.Method, req.URL.Path)
+		}
+		if output != "" {
+			t.Errorf("%s %s: expected no log output, got %s", req.Method, req.URL.Path, output)
+		}
+	}
+
+	output := serveAndCapture(func(w http.ResponseWriter, r *http.Request) {}, httptest.NewRequest("GET", "/api", nil), options...)
+	if output == "" {
+		t.Error("Expected non-skipped request to be logged")
+	}
+}
+
+func TestNewLoggingMiddleware_SkippedRequestKeepsNoLines(t *testing.T) {
+	var contextLogger *logger.ContextLogger
+	handler := NewLoggingMiddleware(WithSkipPaths("/stream"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A long-running skipped request, such as a stream, must not accumulate lines
+		for i := 0; i < 100; i++ {
+			logger.Infof(r.Context(), "chunk %d", i)
+		}
+		contextLogger = logger.FromContext(r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/stream", nil))
+
+	var buf strings.Builder
+	contextLogger.SetOutput(&buf)
+	contextLogger.SetFormatter(formatter.NewJSONFormatter())
+	contextLogger.Flush()
+
+	var logData struct {
+		Runtime struct {
+			Lines []json.RawMessage `json:"lines"`
+		} `json:"runtime"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &logData); err != nil {
+		t.Fatalf("Failed to parse log JSON: %v, output: %s", err, buf.String())
+	}
+	if len(logData.Runtime.Lines) != 0 {
+		t.Errorf("Expected the skipped request logger to keep no lines, got %d", len(logData.Runtime.Lines))
+	}
+}
+
+func TestNewLoggingMiddleware_CaptureHeaders(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-Request-Id", "req-123")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+
+	context := serveAndDecodeContext(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Cache", "HIT")
+		w.Header().Set("Set-Cookie", "session=secret")
+	}, req,
+		WithRequestHeaders("x-request-id", "Accept", "X-Missing"),
+		WithResponseHeaders("X-Cache"),
+	)
+
+	requestHeaders := context["request_headers"].(map[string]interface{})
+	if requestHeaders["X-Request-Id"] != "req-123" {
+		t.Errorf("Expected X-Request-Id to be captured, got %v", requestHeaders)
+	}
+	if requestHeaders["Accept"] != "text/html, application/json" {
+		t.Errorf("Expected joined Accept values, got %v", requestHeaders["Accept"])
+	}
+	if _, exists := requestHeaders["Authorization"]; exists {
+		t.Error("Expected headers outside the allowlist not to be captured")
+	}
+	if _, exists := requestHeaders["X-Missing"]; exists {
+		t.Error("Expected missing headers to be left out")
+	}
+
+	responseHeaders := context["response_headers"].(map[string]interface{})
+	if responseHeaders["X-Cache"] != "HIT" || len(responseHeaders) != 1 {
+		t.Errorf("Unexpected response headers: %v", responseHeaders)
+	}
+}
+
+func TestNewLoggingMiddleware_RenameAndOmitFields(t *testing.T) {
+	context := serveAndDecodeContext(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}, httptest.NewRequest("POST", "/api?x=1", nil),
+		WithFieldName(FieldStatusCode, "status"),
+		WithFieldName(FieldMethod, "http_method"),
+		WithFieldName(FieldHost, ""),
+		WithoutFields(FieldQuery, FieldURL),
+	)
+
+	if context["status"] != float64(http.StatusCreated) {
+		t.Errorf("Expected renamed status field, got %v", context["status"])
+	}
+	if context["http_method"] != "POST" {
+		t.Errorf("Expected renamed method field, got %v", context["http_method"])
+	}
+	for _, omitted := range []string{"status_code", "method", "host", "query", "url"} {
+		if _, exists := context[omitted]; exists {
+			t.Errorf("Expected %s to be omitted", omitted)
+		}
+	}
+	if context["path"] != "/api" {
+		t.Errorf("Expected untouched fields to keep their names, got %v", context["path"])
+	}
+}
+
+func TestNewLoggingMiddleware_RequestLines(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api", nil)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		logger.Infof(r.Context(), "handler line")
+	}
+
+	withLines := serveAndCapture(handler, req)
+	if !strings.Contains(withLines, "Request started") || !strings.Contains(withLines, "Request completed") {
+		t.Errorf("Expected request lines by default, got %s", withLines)
+	}
+
+	withoutLines := serveAndCapture(handler, req, WithRequestLines(false))
+	if strings.Contains(withoutLines, "Request started") || strings.Contains(withoutLines, "Request completed") {
+		t.Errorf("Expected request lines to be disabled, got %s", withoutLines)
+	}
+	if !strings.Contains(withoutLines, "handler line") {
+		t.Errorf("Expected handler lines to be kept, got %s", withoutLines)
+	}
+}
+
+func TestNewLoggingMiddleware_FieldsFunc(t *testing.T) {
+	req := httptest.NewRequest("GET", "/tenants/acme/users", nil)
+	req.Header.Set("X-Tenant", "acme")
+
+	context := serveAndDecodeContext(t, func(w http.ResponseWriter, r *http.Request) {}, req,
+		WithFieldsFunc(func(r *http.Request) map[string]interface{} {
+			return map[string]interface{}{
+				"tenant": r.Header.Get("X-Tenant"),
+				"path":   "/tenants/:tenant/users",
+			}
+		}),
+	)
+
+	if context["tenant"] != "acme" {
+		t.Errorf("Expected custom tenant field, got %v", context["tenant"])
+	}
+	if context["path"] != "/tenants/:tenant/users" {
+		t.Errorf("Expected custom fields to override built-in fields, got %v", context["path"])
+	}
+}
+
+func TestNewLoggingMiddleware_StatusSeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		options  []Option
+		severity string
+	}{
+		{"disabled by default", http.StatusInternalServerError, nil, "INFO"},
+		{"success keeps line severity", http.StatusOK, []Option{WithStatusSeverity(DefaultStatusSeverity)}, "INFO"},
+		{"client error raises to WARN", http.StatusNotFound, []Option{WithStatusSeverity(DefaultStatusSeverity)}, "WARN"},
+		{"server error raises to ERROR", http.StatusBadGateway, []Option{WithStatusSeverity(DefaultStatusSeverity)}, "ERROR"},
+		{"disabled mapping", http.StatusInternalServerError, []Option{WithStatusSeverity(DefaultStatusSeverity), WithStatusSeverity(nil)}, "INFO"},
+		{"custom mapping", http.StatusNotFound, []Option{WithStatusSeverity(func(statusCode int) logger.LogLevel {
+			if statusCode == http.StatusNotFound {
+				return logger.InfoLevel
+			}
+			return DefaultStatusSeverity(statusCode)
+		})}, "INFO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := serveAndCapture(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}, httptest.NewRequest("GET", "/", nil), tt.options...)
+
+			var logData struct {
+				Runtime struct {
+					Severity string `json:"severity"`
+				} `json:"runtime"`
+			}
+			if err := json.Unmarshal([]byte(output), &logData); err != nil {
+				t.Fatalf("Failed to parse log JSON: %v, output: %s", err, output)
+			}
+			if logData.Runtime.Severity != tt.severity {
+				t.Errorf("Expected severity %s for status %d, got %s", tt.severity, tt.status, logData.Runtime.Severity)
+			}
+		})
+	}
+}
+
+func TestNewLoggingMiddleware_LoggerMiddleware(t *testing.T) {
+	logger.ClearMiddleware()
+	defer logger.ClearMiddleware()
+	logger.AddMiddleware(func(entry *logger.LogEntry, next func(*logger.LogEntry)) {
+		entry.Message = "[global] " + entry.Message
+		next(entry)
+	})
+
+	masker := logger.NewPasswordMaskingMiddleware()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		logger.AddContextValue(r.Context(), "api_key", "abc")
+		logger.Infof(r.Context(), "password=secret")
+	}
+
+	output := serveAndCapture(handler, httptest.NewRequest("GET", "/api", nil),
+		WithRequestLines(false),
+		WithMiddleware(masker.Middleware()),
+		WithOutputMiddleware(logger.EnrichContext(func(output *formatter.LogOutput) map[string]interface{} {
+			return map[string]interface{}{"api_key": "***"}
+		})),
+		WithGlobalMiddleware(false),
+	)
+
+	var logData struct {
+		Context map[string]interface{} `json:"context"`
+		Runtime struct {
+			Lines []struct {
+				Message string `json:"message"`
+			} `json:"lines"`
+		} `json:"runtime"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &logData); err != nil {
+		t.Fatalf("Failed to parse log JSON: %v, output: %s", err, output)
+	}
+	if logData.Context["api_key"] != "***" {
+		t.Errorf("Expected context to be rewritten by the request logger's chain, got %v", logData.Context["api_key"])
+	}
+	if len(logData.Runtime.Lines) != 1 || logData.Runtime.Lines[0].Message != "password=***" {
+		t.Errorf("Expected only the request logger's chain, got %+v", logData.Runtime.Lines)
+	}
+
+	// Without the options the global chain applies
+	output = serveAndCapture(handler, httptest.NewRequest("GET", "/api", nil), WithRequestLines(false))
+	if !strings.Contains(output, "[global] password=secret") || !strings.Contains(output, `"api_key":"abc"`) {
+		t.Errorf("Expected only the global chain, got %s", output)
+	}
+}
+
+func TestNewLoggingMiddleware_LoggerOptions(t *testing.T) {
+	output := serveAndCapture(func(w http.ResponseWriter, r *http.Request) {},
+		httptest.NewRequest("GET", "/api", nil),
+		WithLoggerOptions(logger.WithContextLogType("access")))
+
+	if !strings.Contains(output, `"type":"access"`) {
+		t.Errorf("Expected logger options to apply to the request logger, got %s", output)
+	}
+}
@@ -0,0 +1,248 @@
+package http_middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps http.ResponseWriter to capture response information
+// The handler receives it through wrap, which exposes only the optional http.Flusher,
+// http.Hijacker, io.ReaderFrom and http.Pusher interfaces that the wrapped writer implements.
+// http.ResponseController is supported through Unwrap.
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	bytes       int64
+	hijacked    bool
+}
+
+// Optional interfaces of the wrapped writer, used as bits of the capabilityWriters index
+const (
+	canFlush = 1 << iota
+	canHijack
+	canReadFrom
+	canPush
+)
+
+// flushFunc, hijackFunc, readFromFunc and pushFunc adapt the responseWriter methods to
+// the optional interfaces so that they can be embedded only when supported
+type (
+	flushFunc    func()
+	hijackFunc   func() (net.Conn, *bufio.ReadWriter, error)
+	readFromFunc func(io.Reader) (int64, error)
+	pushFunc     func(string, *http.PushOptions) error
+)
+
+func (f flushFunc) Flush() { f() }
+
+func (f hijackFunc) Hijack() (net.Conn, *bufio.ReadWriter, error) { return f() }
+
+func (f readFromFunc) ReadFrom(src io.Reader) (int64, error) { return f(src) }
+
+func (f pushFunc) Push(target string, opts *http.PushOptions) error { return f(target, opts) }
+
+// capabilityWriters builds the writer handed to the handler for each combination of
+// optional interfaces
+var capabilityWriters = [16]func(*responseWriter) http.ResponseWriter{
+	0: func(w *responseWriter) http.ResponseWriter { return w },
+	canFlush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Flusher
+		}{w, flushFunc(w.flush)}
+	},
+	canHijack: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Hijacker
+		}{w, hijackFunc(w.hijack)}
+	},
+	canFlush | canHijack: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{w, flushFunc(w.flush), hijackFunc(w.hijack)}
+	},
+	canReadFrom: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			io.ReaderFrom
+		}{w, readFromFunc(w.readFrom)}
+	},
+	canFlush | canReadFrom: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{w, flushFunc(w.flush), readFromFunc(w.readFrom)}
+	},
+	canHijack | canReadFrom: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{w, hijackFunc(w.hijack), readFromFunc(w.readFrom)}
+	},
+	canFlush | canHijack | canReadFrom: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{w, flushFunc(w.flush), hijackFunc(w.hijack), readFromFunc(w.readFrom)}
+	},
+	canPush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Pusher
+		}{w, pushFunc(w.push)}
+	},
+	canFlush | canPush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+		}{w, flushFunc(w.flush), pushFunc(w.push)}
+	},
+	canHijack | canPush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+		}{w, hijackFunc(w.hijack), pushFunc(w.push)}
+	},
+	canFlush | canHijack | canPush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{w, flushFunc(w.flush), hijackFunc(w.hijack), pushFunc(w.push)}
+	},
+	canReadFrom | canPush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			io.ReaderFrom
+			http.Pusher
+		}{w, readFromFunc(w.readFrom), pushFunc(w.push)}
+	},
+	canFlush | canReadFrom | canPush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Flusher
+			io.ReaderFrom
+			http.Pusher
+		}{w, flushFunc(w.flush), readFromFunc(w.readFrom), pushFunc(w.push)}
+	},
+	canHijack | canReadFrom | canPush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{w, hijackFunc(w.hijack), readFromFunc(w.readFrom), pushFunc(w.push)}
+	},
+	canFlush | canHijack | canReadFrom | canPush: func(w *responseWriter) http.ResponseWriter {
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{w, flushFunc(w.flush), hijackFunc(w.hijack), readFromFunc(w.readFrom), pushFunc(w.push)}
+	},
+}
+
+// wrap returns the writer handed to the handler, which implements the same optional
+// interfaces as the wrapped writer so that type assertions of the handler stay accurate
+func (rw *responseWriter) wrap() http.ResponseWriter {
+	capabilities := 0
+	if _, ok := rw.ResponseWriter.(http.Flusher); ok {
+		capabilities |= canFlush
+	}
+	if _, ok := rw.ResponseWriter.(http.Hijacker); ok {
+		capabilities |= canHijack
+	}
+	if _, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		capabilities |= canReadFrom
+	}
+	if _, ok := rw.ResponseWriter.(http.Pusher); ok {
+		capabilities |= canPush
+	}
+	return capabilityWriters[capabilities](rw)
+}
+
+// WriteHeader captures the status code
+func (rw *responseWriter) WriteHeader(code int) {
+	// Informational responses other than 101 Switching Protocols may precede the final status
+	if !rw.wroteHeader && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write counts the bytes written to the response body
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// readFrom uses the io.ReaderFrom of the wrapped writer, such as the sendfile path of
+// net/http, and counts the bytes written
+func (rw *responseWriter) readFrom(src io.Reader) (int64, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	rw.bytes += n
+	return n, err
+}
+
+// flush sends buffered data to the client through the http.Flusher of the wrapped writer
+func (rw *responseWriter) flush() {
+	rw.wroteHeader = true
+	rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+// hijack lets the handler take over the connection through the http.Hijacker of the
+// wrapped writer
+func (rw *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := rw.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		rw.hijacked = true
+		if !rw.wroteHeader {
+			// The handler writes the response itself, typically a protocol upgrade
+			rw.statusCode = http.StatusSwitchingProtocols
+			rw.wroteHeader = true
+		}
+	}
+	return conn, buf, err
+}
+
+// push initiates an HTTP/2 server push through the http.Pusher of the wrapped writer
+func (rw *responseWriter) push(target string, opts *http.PushOptions) error {
+	return rw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// countingReadCloser wraps a request body to count the bytes read by the handler
+type countingReadCloser struct {
+	io.ReadCloser
+	bytes int64
+}
+
+// Read counts the bytes read from the body
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.bytes += int64(n)
+	return n, err
+}
@@ -0,0 +1,244 @@
+package http_middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zentooo/logspan/logger"
+)
+
+func TestLoggingMiddleware_ByteCountsAndProtocol(t *testing.T) {
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(`{"name":"logspan"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	context := serveAndDecodeContext(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("hello"))
+		w.Write([]byte(", world"))
+	}, req)
+
+	if context["request_bytes"] != float64(18) {
+		t.Errorf("Expected request_bytes 18, got %v", context["request_bytes"])
+	}
+	if context["response_bytes"] != float64(12) {
+		t.Errorf("Expected response_bytes 12, got %v", context["response_bytes"])
+	}
+	if context["proto"] != "HTTP/1.1" {
+		t.Errorf("Expected proto HTTP/1.1, got %v", context["proto"])
+	}
+	if context["content_type"] != "application/json" {
+		t.Errorf("Expected request content_type, got %v", context["content_type"])
+	}
+	if context["response_content_type"] != "text/plain; charset=utf-8" {
+		t.Errorf("Expected response_content_type, got %v", context["response_content_type"])
+	}
+	if _, exists := context["tls_version"]; exists {
+		t.Error("Expected no tls_version for a plain HTTP request")
+	}
+}
+
+func TestLoggingMiddleware_TLSVersion(t *testing.T) {
+	var logOutput bytes.Buffer
+	server := httptest.NewTLSServer(LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).SetOutput(&logOutput)
+	})))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	var logData map[string]interface{}
+	if err := json.Unmarshal(logOutput.Bytes(), &logData); err != nil {
+		t.Fatalf("Failed to parse log JSON: %v", err)
+	}
+	tlsVersion, _ := logData["context"].(map[string]interface{})["tls_version"].(string)
+	if !strings.HasPrefix(tlsVersion, "TLS 1.") {
+		t.Errorf("Expected tls_version to be recorded, got %q", tlsVersion)
+	}
+}
+
+func TestResponseWriter_PreservesFlusher(t *testing.T) {
+	rr := httptest.NewRecorder()
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).SetOutput(io.Discard)
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("Expected wrapped writer to implement http.Flusher")
+		}
+		w.Write([]byte("event: ping\n\n"))
+		flusher.Flush()
+
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Expected ResponseController to reach the underlying writer: %v", err)
+		}
+	}))
+
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/events", nil))
+	if !rr.Flushed {
+		t.Error("Expected the underlying writer to be flushed")
+	}
+}
+
+func TestResponseWriter_PreservesHijacker(t *testing.T) {
+	var logOutput bytes.Buffer
+	done := make(chan struct{})
+	loggingHandler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).SetOutput(&logOutput)
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("Expected wrapped writer to implement http.Hijacker")
+			return
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			t.Errorf("Hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+		buf.Flush()
+	}))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		loggingHandler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "test")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Expected 101 from hijacked connection, got %d", resp.StatusCode)
+	}
+
+	// The log is flushed after the handler returns
+	<-done
+	scanner := bufio.NewScanner(&logOutput)
+	if !scanner.Scan() {
+		t.Fatal("Expected log output for the hijacked request")
+	}
+	if !strings.Contains(scanner.Text(), `"status_code":101`) {
+		t.Errorf("Expected status_code 101 for the hijacked request, got %s", scanner.Text())
+	}
+}
+
+// readerFromRecorder is a ResponseRecorder that records whether ReadFrom was used
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	usedReadFrom bool
+}
+
+func (r *readerFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	r.usedReadFrom = true
+	return io.Copy(r.ResponseRecorder, src)
+}
+
+func TestResponseWriter_PreservesReaderFrom(t *testing.T) {
+	writer := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	wrapped := &responseWriter{ResponseWriter: writer, statusCode: http.StatusOK}
+
+	// Hide WriteTo of the reader so that io.Copy goes through ReadFrom
+	n, err := io.Copy(wrapped.wrap(), struct{ io.Reader }{strings.NewReader("file contents")})
+	if err != nil || n != 13 {
+		t.Fatalf("io.Copy = %d, %v", n, err)
+	}
+	if !writer.usedReadFrom {
+		t.Error("Expected io.Copy to use the underlying io.ReaderFrom")
+	}
+	if wrapped.bytes != 13 {
+		t.Errorf("Expected 13 counted bytes, got %d", wrapped.bytes)
+	}
+}
+
+// plainWriter is a ResponseWriter without any optional interface
+type plainWriter struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *plainWriter) Header() http.Header         { return w.header }
+func (w *plainWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *plainWriter) WriteHeader(int)             {}
+
+// pusherRecorder is a ResponseRecorder that supports HTTP/2 server push like an HTTP/2 writer
+type pusherRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (r *pusherRecorder) Push(target string, _ *http.PushOptions) error {
+	r.pushed = append(r.pushed, target)
+	return nil
+}
+
+func TestResponseWriter_MatchesUnderlyingCapabilities(t *testing.T) {
+	// An HTTP/2-like writer flushes and pushes but cannot hijack
+	writer := &pusherRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).SetOutput(io.Discard)
+		if _, ok := w.(http.Hijacker); ok {
+			t.Error("Expected wrapped writer not to implement http.Hijacker")
+		}
+		if _, ok := w.(io.ReaderFrom); ok {
+			t.Error("Expected wrapped writer not to implement io.ReaderFrom")
+		}
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Expected wrapped writer to implement http.Flusher")
+		}
+		pusher, ok := w.(http.Pusher)
+		if !ok {
+			t.Fatal("Expected wrapped writer to implement http.Pusher")
+		}
+		if err := pusher.Push("/style.css", nil); err != nil {
+			t.Errorf("Push failed: %v", err)
+		}
+		if _, _, err := http.NewResponseController(w).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Expected ResponseController Hijack to be unsupported, got %v", err)
+		}
+	}))
+
+	handler.ServeHTTP(writer, httptest.NewRequest("GET", "/", nil))
+	if len(writer.pushed) != 1 || writer.pushed[0] != "/style.css" {
+		t.Errorf("Expected the push to reach the underlying writer, got %v", writer.pushed)
+	}
+
+	// A writer without optional interfaces is wrapped without them
+	plain := (&responseWriter{ResponseWriter: &plainWriter{header: http.Header{}}}).wrap()
+	if _, ok := plain.(http.Flusher); ok {
+		t.Error("Expected plain wrapped writer not to implement http.Flusher")
+	}
+	if _, ok := plain.(http.Pusher); ok {
+		t.Error("Expected plain wrapped writer not to implement http.Pusher")
+	}
+}
+
+func TestResponseWriter_StatusCode(t *testing.T) {
+	wrapped := &responseWriter{ResponseWriter: httptest.NewRecorder(), statusCode: http.StatusOK}
+	wrapped.WriteHeader(http.StatusEarlyHints)
+	wrapped.WriteHeader(http.StatusAccepted)
+	wrapped.WriteHeader(http.StatusInternalServerError)
+
+	if wrapped.statusCode != http.StatusAccepted {
+		t.Errorf("Expected the first final status 202, got %d", wrapped.statusCode)
+	}
+	if wrapped.Unwrap() == nil {
+		t.Error("Expected Unwrap to return the wrapped writer")
+	}
+}
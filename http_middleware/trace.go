@@ -0,0 +1,77 @@
+package http_middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zentooo/logspan/logger"
+)
+
+// W3C Trace Context header names
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// traceContextFromRequest derives the trace context of the current request from its
+// traceparent and tracestate headers. A new trace is started when the headers are
+// missing or invalid.
+func traceContextFromRequest(r *http.Request) logger.TraceContext {
+	parent, err := logger.ParseTraceparent(r.Header.Get(TraceparentHeader))
+	if err != nil {
+		return logger.NewTraceContext()
+	}
+
+	// Multiple tracestate headers are combined as a single comma-separated list
+	parent.TraceState = strings.Join(r.Header.Values(TracestateHeader), ",")
+	return parent.Child()
+}
+
+// TraceTransport is an http.RoundTripper that propagates the trace context of the
+// request context to outgoing requests through the traceparent and tracestate headers.
+// When a logspan span is active in the context, its ID is sent as the parent ID so that
+// the downstream service is attached to that span.
+type TraceTransport struct {
+	// Base is the underlying RoundTripper; http.DefaultTransport is used when nil
+	Base http.RoundTripper
+}
+
+// NewTraceTransport creates a new TraceTransport wrapping the given RoundTripper
+//
+// Usage:
+//
+//	client := &http.Client{Transport: http_middleware.NewTraceTransport(nil)}
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//	resp, err := client.Do(req)
+func NewTraceTransport(base http.RoundTripper) *TraceTransport {
+	return &TraceTransport{Base: base}
+}
+
+// RoundTrip injects the trace headers and executes the request with the base RoundTripper
+// Requests whose context carries no trace context are sent unchanged
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	traceContext, ok := logger.TraceContextFromContext(req.Context())
+	if !ok || !traceContext.IsValid() {
+		return base.RoundTrip(req)
+	}
+
+	if span := logger.SpanFromContext(req.Context()); span != nil {
+		traceContext.SpanID = span.ID()
+	}
+
+	// RoundTrippers must not modify the original request
+	outgoing := req.Clone(req.Context())
+	outgoing.Header.Set(TraceparentHeader, traceContext.Traceparent())
+	if traceContext.TraceState != "" {
+		outgoing.Header.Set(TracestateHeader, traceContext.TraceState)
+	} else {
+		outgoing.Header.Del(TracestateHeader)
+	}
+
+	return base.RoundTrip(outgoing)
+}
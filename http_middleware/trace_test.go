@@ -0,0 +1,156 @@
+package http_middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zentooo/logspan/logger"
+)
+
+// serveAndCapture serves the request through a logging middleware built with the options
+// and returns everything the request logger wrote
+func serveAndCapture(handler http.HandlerFunc, req *http.Request, options ...Option) string {
+	var logOutput bytes.Buffer
+	wrapped := NewLoggingMiddleware(options...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).SetOutput(&logOutput)
+		handler(w, r)
+	}))
+	wrapped.ServeHTTP(httptest.NewRecorder(), req)
+	return logOutput.String()
+}
+
+// serveAndDecodeContext serves the request through a logging middleware built with the
+// options and returns the context section of the flushed log
+func serveAndDecodeContext(t *testing.T, handler http.HandlerFunc, req *http.Request, options ...Option) map[string]interface{} {
+	t.Helper()
+
+	output := serveAndCapture(handler, req, options...)
+	var logData map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &logData); err != nil {
+		t.Fatalf("Failed to parse log JSON: %v, output: %s", err, output)
+	}
+	return logData["context"].(map[string]interface{})
+}
+
+func TestLoggingMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	req := httptest.NewRequest("GET", "/traced", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Add("tracestate", "vendor1=a")
+	req.Header.Add("tracestate", "vendor2=b")
+
+	var seen logger.TraceContext
+	context := serveAndDecodeContext(t, func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = logger.TraceContextFromContext(r.Context())
+	}, req)
+
+	if context["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected incoming trace_id, got %v", context["trace_id"])
+	}
+	if context["parent_span_id"] != "00f067aa0ba902b7" {
+		t.Errorf("Expected parent_span_id from header, got %v", context["parent_span_id"])
+	}
+	if context["span_id"] == "00f067aa0ba902b7" || len(context["span_id"].(string)) != 16 {
+		t.Errorf("Expected a new span_id for this service, got %v", context["span_id"])
+	}
+	if context["trace_flags"] != "01" {
+		t.Errorf("Expected trace_flags 01, got %v", context["trace_flags"])
+	}
+	if seen.SpanID != context["span_id"] || seen.TraceState != "vendor1=a,vendor2=b" {
+		t.Errorf("Unexpected trace context in request context: %+v", seen)
+	}
+}
+
+func TestLoggingMiddleware_StartsNewTrace(t *testing.T) {
+	for _, header := range []string{"", "00-invalid"} {
+		req := httptest.NewRequest("GET", "/untraced", nil)
+		if header != "" {
+			req.Header.Set("traceparent", header)
+		}
+
+		var traceID string
+		context := serveAndDecodeContext(t, func(w http.ResponseWriter, r *http.Request) {
+			traceID = logger.TraceIDFromContext(r.Context())
+		}, req)
+
+		if len(traceID) != 32 || context["trace_id"] != traceID {
+			t.Errorf("Expected generated trace_id in context and logs, got %q / %v", traceID, context["trace_id"])
+		}
+		if _, exists := context["parent_span_id"]; exists {
+			t.Error("Expected no parent_span_id for a new trace")
+		}
+	}
+}
+
+func TestTraceTransport_InjectsHeaders(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTraceTransport(nil)}
+	traceContext := logger.TraceContext{
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:     "00f067aa0ba902b7",
+		Flags:      logger.TraceFlagSampled,
+		TraceState: "vendor=a",
+	}
+	ctx := logger.WithTraceContext(logger.WithLogger(context.Background(), logger.NewContextLogger()), traceContext)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if received.Get("traceparent") != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("Unexpected traceparent: %s", received.Get("traceparent"))
+	}
+	if received.Get("tracestate") != "vendor=a" {
+		t.Errorf("Unexpected tracestate: %s", received.Get("tracestate"))
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("Expected the original request not to be modified")
+	}
+
+	// An active logspan span becomes the parent of the outgoing call
+	spanCtx, end := logger.StartSpan(ctx, "call.downstream")
+	defer end()
+	req, _ = http.NewRequestWithContext(spanCtx, http.MethodGet, server.URL, nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + logger.SpanFromContext(spanCtx).ID() + "-01"
+	if received.Get("traceparent") != expected {
+		t.Errorf("Expected traceparent %s, got %s", expected, received.Get("traceparent"))
+	}
+}
+
+func TestTraceTransport_WithoutTraceContext(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTraceTransport(http.DefaultTransport)}
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if received.Get("traceparent") != "" {
+		t.Errorf("Expected no traceparent, got %s", received.Get("traceparent"))
+	}
+}
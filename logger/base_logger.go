@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"sync"
 
@@ -12,7 +13,12 @@ type BaseLogger struct {
 	output    io.Writer
 	minLevel  LogLevel
 	formatter formatter.Formatter
+	sink      Sink // When set, documents go to the sink instead of output and formatter
 	mutex     sync.Mutex
+
+	middleware           *MiddlewareChain       // Entry middleware of this logger, run after the global chain
+	outputMiddleware     *OutputMiddlewareChain // Output middleware of this logger, run after the global chain
+	skipGlobalMiddleware bool                   // Use only the logger's chains, see SetGlobalMiddleware
 }
 
 // newBaseLogger creates a new BaseLogger with default settings
@@ -25,10 +31,13 @@ func newBaseLogger() BaseLogger {
 }
 
 // SetOutput sets the output writer for the logger
+// It removes the logger's sink, including one inherited from the global Sinks, so that
+// documents go to w. Call SetSink afterwards to use a sink again.
 func (b *BaseLogger) SetOutput(w io.Writer) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 	b.output = w
+	b.sink = nil
 }
 
 // SetLevel sets the minimum log level for filtering
@@ -39,10 +48,62 @@ func (b *BaseLogger) SetLevel(level LogLevel) {
 }
 
 // SetFormatter sets the formatter for the logger
+// Like SetOutput, it removes the logger's sink, which would otherwise bypass the formatter.
 func (b *BaseLogger) SetFormatter(f formatter.Formatter) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 	b.formatter = f
+	b.sink = nil
+}
+
+// SetSink sets a sink that receives the documents of the logger instead of its output
+// and formatter. Passing nil restores writing to the output.
+func (b *BaseLogger) SetSink(s Sink) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.sink = s
+}
+
+// AddMiddleware adds a middleware to the logger's own middleware chain
+// The logger's chain runs after the global chain (see SetGlobalMiddleware) and applies only to
+// the entries of this logger.
+func (b *BaseLogger) AddMiddleware(middleware Middleware) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.middleware == nil {
+		b.middleware = NewMiddlewareChain()
+	}
+	b.middleware.Add(middleware)
+}
+
+// AddOutputMiddleware adds an output middleware to the logger's own output middleware chain
+// The logger's chain runs after the global chain (see SetGlobalMiddleware) and applies only to
+// the documents of this logger.
+func (b *BaseLogger) AddOutputMiddleware(middleware OutputMiddleware) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.outputMiddleware == nil {
+		b.outputMiddleware = NewOutputMiddlewareChain()
+	}
+	b.outputMiddleware.Add(middleware)
+}
+
+// ClearMiddleware removes all middleware and output middleware from the logger's own chains
+// The global chains are not affected.
+func (b *BaseLogger) ClearMiddleware() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.middleware = nil
+	b.outputMiddleware = nil
+}
+
+// SetGlobalMiddleware enables or disables the global middleware chains for the logger
+// When disabled, only the logger's own chains are applied, so that the logger is not affected
+// by AddMiddleware, AddOutputMiddleware or ClearMiddleware at package level. Enabled by default.
+func (b *BaseLogger) SetGlobalMiddleware(enabled bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.skipGlobalMiddleware = !enabled
 }
 
 // SetLevelFromString sets the minimum log level from a string
@@ -70,3 +131,88 @@ func (b *BaseLogger) getFormatter() formatter.Formatter {
 	defer b.mutex.Unlock()
 	return b.formatter
 }
+
+// hasDestination reports whether documents have somewhere to go
+// This method assumes the mutex is already held by the caller
+func (b *BaseLogger) hasDestination() bool {
+	return b.sink != nil || b.output != nil
+}
+
+// writeLogOutput passes the document through the global and the logger's output middleware
+// chains and then to the sink, or formats it and writes it to the output
+// It returns false if the document could not be formatted. A document dropped by an output
+// middleware counts as written.
+// This method assumes the mutex is already held by the caller
+func (b *BaseLogger) writeLogOutput(logOutput *formatter.LogOutput) bool {
+	written := true
+	final := func(processed *formatter.LogOutput) {
+		written = b.emitLogOutput(processed)
+	}
+	if b.outputMiddleware != nil {
+		chain, emit := b.outputMiddleware, final
+		final = func(processed *formatter.LogOutput) {
+			chain.Process(processed, emit)
+		}
+	}
+
+	if b.skipGlobalMiddleware {
+		final(logOutput)
+	} else {
+		processWithGlobalOutputMiddleware(logOutput, final)
+	}
+	return written
+}
+
+// processEntry passes the entry through the global middleware chain and then the logger's
+// own chain before calling final
+// This method assumes the mutex is already held by the caller
+func (b *BaseLogger) processEntry(entry *LogEntry, final func(*LogEntry)) {
+	if b.middleware != nil {
+		chain, store := b.middleware, final
+		final = func(processed *LogEntry) {
+			chain.Process(processed, store)
+		}
+	}
+
+	if b.skipGlobalMiddleware {
+		final(entry)
+		return
+	}
+	processWithGlobalMiddleware(entry, final)
+}
+
+// emitLogOutput passes the document to the sink, or formats it and writes it to the output
+// It returns false if the document could not be formatted.
+// This method assumes the mutex is already held by the caller
+func (b *BaseLogger) emitLogOutput(logOutput *formatter.LogOutput) bool {
+	if b.sink != nil {
+		if err := writeToSink(b.sink, logOutput); err != nil {
+			handleError("sink", err)
+		}
+		return true
+	}
+
+	// Use the formatter (default or explicitly set)
+	records, err := formatRecordsWithLimit(logOutput, b.formatter, GetConfig().MaxOutputBytes)
+	if err != nil {
+		// Handle formatting error using error handler
+		handleError("format", err)
+		// Fallback to simple output if formatting fails
+		_, writeErr := fmt.Fprintf(b.output, "Error formatting log: %v\n", err)
+		if writeErr != nil {
+			handleError("write_fallback", writeErr)
+		}
+		return false
+	}
+
+	if err := writeRecords(b.output, records); err != nil {
+		// Handle write error using error handler
+		handleError("write", err)
+		// Try to write an error message as fallback
+		_, fallbackErr := fmt.Fprintf(b.output, "Error writing log output: %v\n", err)
+		if fallbackErr != nil {
+			handleError("write_error_fallback", fallbackErr)
+		}
+	}
+	return true
+}
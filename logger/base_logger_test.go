@@ -2,6 +2,7 @@ package logger
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/zentooo/logspan/formatter"
@@ -192,3 +193,88 @@ func TestBaseLogger_GettersThreadSafety(t *testing.T) {
 		<-done
 	}
 }
+
+func TestBaseLogger_LoggerMiddleware(t *testing.T) {
+	ClearMiddleware()
+	defer ClearMiddleware()
+
+	var order []string
+	AddMiddleware(func(entry *LogEntry, next func(*LogEntry)) {
+		order = append(order, "global")
+		next(entry)
+	})
+
+	var buf bytes.Buffer
+	directLogger := NewDirectLogger()
+	directLogger.SetOutput(&buf)
+	directLogger.AddMiddleware(func(entry *LogEntry, next func(*LogEntry)) {
+		order = append(order, "logger")
+		entry.Message = "[audit] " + entry.Message
+		next(entry)
+	})
+	directLogger.Infof("message")
+
+	if len(order) != 2 || order[0] != "global" || order[1] != "logger" {
+		t.Errorf("Expected the global chain before the logger's chain, got %v", order)
+	}
+	if !strings.Contains(buf.String(), "[audit] message") {
+		t.Errorf("Expected logger middleware to apply, got %s", buf.String())
+	}
+
+	// Other loggers are not affected
+	order = nil
+	buf.Reset()
+	otherLogger := NewDirectLogger()
+	otherLogger.SetOutput(&buf)
+	otherLogger.Infof("message")
+	if len(order) != 1 || strings.Contains(buf.String(), "[audit]") {
+		t.Errorf("Expected only the global chain for other loggers, got %v, %s", order, buf.String())
+	}
+
+	// Without the global chain
+	order = nil
+	directLogger.SetGlobalMiddleware(false)
+	directLogger.Infof("message")
+	if len(order) != 1 || order[0] != "logger" {
+		t.Errorf("Expected only the logger's chain, got %v", order)
+	}
+
+	// Clearing the logger's chain leaves the global chain
+	order = nil
+	directLogger.SetGlobalMiddleware(true)
+	directLogger.ClearMiddleware()
+	directLogger.Infof("message")
+	if len(order) != 1 || order[0] != "global" || GetMiddlewareCount() != 1 {
+		t.Errorf("Expected only the global chain after ClearMiddleware, got %v", order)
+	}
+}
+
+func TestBaseLogger_LoggerOutputMiddleware(t *testing.T) {
+	ClearOutputMiddleware()
+	defer ClearOutputMiddleware()
+
+	AddOutputMiddleware(func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		output.Type += "+global"
+		next(output)
+	})
+
+	var buf bytes.Buffer
+	directLogger := NewDirectLogger()
+	directLogger.SetOutput(&buf)
+	directLogger.AddOutputMiddleware(func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		output.Type += "+logger"
+		next(output)
+	})
+	directLogger.Infof("message")
+
+	if !strings.Contains(buf.String(), `"type":"request+global+logger"`) {
+		t.Errorf("Expected both output chains in order, got %s", buf.String())
+	}
+
+	buf.Reset()
+	directLogger.SetGlobalMiddleware(false)
+	directLogger.Infof("message")
+	if !strings.Contains(buf.String(), `"type":"request+logger"`) {
+		t.Errorf("Expected only the logger's output chain, got %s", buf.String())
+	}
+}
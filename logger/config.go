@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/zentooo/logspan/formatter"
 )
@@ -36,6 +37,31 @@ type Config struct {
 	// FlushEmpty enables flushing even when there are no log entries
 	// Useful for HTTP request logging to record request context even without logs
 	FlushEmpty bool
+
+	// Sinks receive the log documents instead of Output when set
+	Sinks []Sink
+
+	// MaxMessageLength is the maximum length of a message in bytes
+	// 0 means no limit
+	MaxMessageLength int
+
+	// HeadLines and TailLines limit the lines a ContextLogger keeps per flush to the first
+	// HeadLines and the last TailLines. Both 0 means no limit
+	HeadLines int
+	TailLines int
+
+	// MaxOutputBytes is the maximum size of a formatted document in bytes
+	// 0 means no limit
+	MaxOutputBytes int
+
+	// Sampler decides at flush time whether a ContextLogger aggregate is written
+	// nil means every aggregate is written
+	Sampler *Sampler
+
+	// RateLimitBurst and RateLimitInterval limit the global DirectLogger (D) to RateLimitBurst
+	// messages per message template in each RateLimitInterval. 0 means no limit
+	RateLimitBurst    int
+	RateLimitInterval time.Duration
 }
 
 // Option is a function that configures the logger
@@ -99,6 +125,61 @@ func WithFlushEmpty(enabled bool) Option {
 	}
 }
 
+// WithSinks sends log documents to the given sinks instead of Output
+// Each sink can have its own minimum level and formatter (see NewWriterSink).
+// With several sinks, a failing sink does not prevent the others from receiving documents.
+func WithSinks(sinks ...Sink) Option {
+	return func(c *Config) {
+		c.Sinks = append(c.Sinks, sinks...)
+	}
+}
+
+// WithMaxMessageLength truncates messages longer than maxBytes bytes and marks the line
+// with "truncated": true. 0 means no limit
+func WithMaxMessageLength(maxBytes int) Option {
+	return func(c *Config) {
+		c.MaxMessageLength = maxBytes
+	}
+}
+
+// WithLineLimit keeps at most the first head and the last tail lines of each ContextLogger
+// flush. Lines in between are dropped as they are logged and counted in "droppedLines";
+// the aggregated severity still accounts for them. Both 0 means no limit
+func WithLineLimit(head, tail int) Option {
+	return func(c *Config) {
+		c.HeadLines = head
+		c.TailLines = tail
+	}
+}
+
+// WithMaxOutputBytes limits the size of each formatted document to maxBytes bytes by
+// truncating the messages of lines that do not fit on their own and dropping lines from
+// the middle of the document. 0 means no limit
+func WithMaxOutputBytes(maxBytes int) Option {
+	return func(c *Config) {
+		c.MaxOutputBytes = maxBytes
+	}
+}
+
+// WithSampler samples ContextLogger aggregates at flush time with the given sampler
+// Sampled-out aggregates are discarded and counted (see GetSamplingStats).
+// DirectLogger lines are not sampled
+func WithSampler(sampler *Sampler) Option {
+	return func(c *Config) {
+		c.Sampler = sampler
+	}
+}
+
+// WithRateLimit limits the global DirectLogger (D) to burst messages per message template in each
+// interval. Further messages are suppressed and reported by a single summary line at the end of
+// the interval (see DirectLogger.SetRateLimit). 0 means no limit
+func WithRateLimit(burst int, interval time.Duration) Option {
+	return func(c *Config) {
+		c.RateLimitBurst = burst
+		c.RateLimitInterval = interval
+	}
+}
+
 // defaultConfig returns a default configuration
 func defaultConfig() Config {
 	return Config{
@@ -154,6 +235,8 @@ func Init(options ...Option) {
 			jsonFormatter = formatter.NewJSONFormatter()
 		}
 		directLogger.SetFormatter(jsonFormatter)
+		directLogger.SetSink(newSinkFromConfig(globalConfig.Sinks))
+		directLogger.SetRateLimit(globalConfig.RateLimitBurst, globalConfig.RateLimitInterval)
 	}
 }
 
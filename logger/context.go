@@ -3,6 +3,7 @@ package logger
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 // contextKey is a private type for context keys to avoid collisions
@@ -18,10 +19,16 @@ func WithLogger(ctx context.Context, logger *ContextLogger) context.Context {
 	return context.WithValue(ctx, loggerContextKey, logger)
 }
 
+// loggerFromContext retrieves the logger from the context without emitting a warning
+func loggerFromContext(ctx context.Context) (*ContextLogger, bool) {
+	logger, ok := ctx.Value(loggerContextKey).(*ContextLogger)
+	return logger, ok
+}
+
 // FromContext retrieves the logger from the context
 // If no logger is found, it returns a new ContextLogger and emits a warning
 func FromContext(ctx context.Context) *ContextLogger {
-	if logger, ok := ctx.Value(loggerContextKey).(*ContextLogger); ok {
+	if logger, ok := loggerFromContext(ctx); ok {
 		return logger
 	}
 
@@ -48,34 +55,70 @@ func AddContextValues(ctx context.Context, fields map[string]interface{}) {
 	logger.AddContextValues(fields)
 }
 
+// RaiseSeverity raises the aggregate severity of the logger in the context to at least the given level
+func RaiseSeverity(ctx context.Context, level LogLevel) {
+	logger := FromContext(ctx)
+	logger.RaiseSeverity(level)
+}
+
 // Infof logs an info message using the logger from context
 func Infof(ctx context.Context, format string, args ...interface{}) {
 	logger := FromContext(ctx)
-	logger.Infof(format, args...)
+	logger.addEntry(InfoLevel, fmt.Sprintf(format, args...), nil, logger.activeSpan(ctx))
 }
 
 // Debugf logs a debug message using the logger from context
 func Debugf(ctx context.Context, format string, args ...interface{}) {
 	logger := FromContext(ctx)
-	logger.Debugf(format, args...)
+	logger.addEntry(DebugLevel, fmt.Sprintf(format, args...), nil, logger.activeSpan(ctx))
 }
 
 // Warnf logs a warning message using the logger from context
 func Warnf(ctx context.Context, format string, args ...interface{}) {
 	logger := FromContext(ctx)
-	logger.Warnf(format, args...)
+	logger.addEntry(WarnLevel, fmt.Sprintf(format, args...), nil, logger.activeSpan(ctx))
 }
 
 // Errorf logs an error message using the logger from context
 func Errorf(ctx context.Context, format string, args ...interface{}) {
 	logger := FromContext(ctx)
-	logger.Errorf(format, args...)
+	logger.addEntry(ErrorLevel, fmt.Sprintf(format, args...), nil, logger.activeSpan(ctx))
 }
 
 // Criticalf logs a critical message using the logger from context
 func Criticalf(ctx context.Context, format string, args ...interface{}) {
 	logger := FromContext(ctx)
-	logger.Criticalf(format, args...)
+	logger.addEntry(CriticalLevel, fmt.Sprintf(format, args...), nil, logger.activeSpan(ctx))
+}
+
+// Debugw logs a debug message with structured key/value fields using the logger from context
+func Debugw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	logger := FromContext(ctx)
+	logger.addEntry(DebugLevel, msg, fieldsFromKeysAndValues(keysAndValues), logger.activeSpan(ctx))
+}
+
+// Infow logs an info message with structured key/value fields using the logger from context
+func Infow(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	logger := FromContext(ctx)
+	logger.addEntry(InfoLevel, msg, fieldsFromKeysAndValues(keysAndValues), logger.activeSpan(ctx))
+}
+
+// Warnw logs a warning message with structured key/value fields using the logger from context
+func Warnw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	logger := FromContext(ctx)
+	logger.addEntry(WarnLevel, msg, fieldsFromKeysAndValues(keysAndValues), logger.activeSpan(ctx))
+}
+
+// Errorw logs an error message with structured key/value fields using the logger from context
+func Errorw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	logger := FromContext(ctx)
+	logger.addEntry(ErrorLevel, msg, fieldsFromKeysAndValues(keysAndValues), logger.activeSpan(ctx))
+}
+
+// Criticalw logs a critical message with structured key/value fields using the logger from context
+func Criticalw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	logger := FromContext(ctx)
+	logger.addEntry(CriticalLevel, msg, fieldsFromKeysAndValues(keysAndValues), logger.activeSpan(ctx))
 }
 
 // FlushContext flushes the logger from the context
@@ -2,10 +2,10 @@ package logger
 
 import (
 	"fmt"
-	"os"
-	"runtime"
-	"strings"
+	"io"
 	"time"
+
+	"github.com/zentooo/logspan/formatter"
 )
 
 // ContextLogger implements context-based logging with log aggregation
@@ -13,18 +13,142 @@ type ContextLogger struct {
 	*BaseLogger
 	entries    []*LogEntry
 	fields     map[string]interface{}
+	spans      []*Span  // Spans reported in the next flush, in start order
+	severity   LogLevel // Minimum aggregate severity of the next flush, see RaiseSeverity
 	startTime  time.Time
-	maxEntries int // Maximum number of entries before auto-flush
+	maxEntries int    // Maximum number of entries before auto-flush
+	logType    string // Type field of the output; empty uses the global LogType
+
+	headLines    int // Number of first lines kept per flush, see WithLineLimit
+	tailLines    int // Number of last lines kept per flush, see WithLineLimit
+	droppedLines int // Lines dropped since the last flush
+
+	sampler *Sampler // Decides whether a flush is written, see WithSampler
+}
+
+// ContextLoggerOption is a function that configures a single ContextLogger
+type ContextLoggerOption func(*ContextLogger)
+
+// WithContextOutput sets the output of the logger instead of the global Output
+// The logger writes to output even when global Sinks are configured; of WithContextOutput
+// and WithContextSink, the last one given applies
+func WithContextOutput(output io.Writer) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.output = output
+		l.sink = nil
+	}
+}
+
+// WithContextFormatter sets the formatter of the logger instead of the global default
+// Like WithContextOutput, it makes the logger write to its output instead of the global Sinks
+func WithContextFormatter(f formatter.Formatter) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.formatter = f
+		l.sink = nil
+	}
+}
+
+// WithContextMinLevel sets the minimum log level of the logger instead of the global MinLevel
+func WithContextMinLevel(level LogLevel) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.minLevel = level
+	}
+}
+
+// WithContextLogType sets the type field of the logger's output instead of the global LogType
+func WithContextLogType(logType string) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.logType = logType
+	}
+}
+
+// WithContextMaxLogEntries sets the auto-flush threshold of the logger instead of the global
+// MaxLogEntries. 0 means no limit (manual flush only)
+func WithContextMaxLogEntries(count int) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.maxEntries = count
+	}
+}
+
+// WithContextLineLimit keeps at most the first head and the last tail lines of each flush
+// instead of the global line limit. Both 0 means no limit
+func WithContextLineLimit(head, tail int) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.headLines = head
+		l.tailLines = tail
+	}
+}
+
+// WithContextSampler sets the sampler of the logger instead of the global Sampler
+// Passing nil writes every flush
+func WithContextSampler(sampler *Sampler) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.sampler = sampler
+	}
+}
+
+// WithContextFields sets initial context fields of the logger
+func WithContextFields(fields map[string]interface{}) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		for k, v := range fields {
+			l.fields[k] = v
+		}
+	}
+}
+
+// WithContextSink sets the sink of the logger instead of the global sinks
+// Passing nil makes the logger write to its output. Of WithContextSink and
+// WithContextOutput or WithContextFormatter, the last one given applies
+func WithContextSink(s Sink) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.sink = s
+	}
+}
+
+// WithContextMiddleware adds middleware to the logger's own middleware chain, which runs
+// after the global chain and applies only to this logger
+func WithContextMiddleware(middlewares ...Middleware) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		if l.middleware == nil {
+			l.middleware = NewMiddlewareChain()
+		}
+		for _, middleware := range middlewares {
+			l.middleware.Add(middleware)
+		}
+	}
+}
+
+// WithContextOutputMiddleware adds output middleware to the logger's own output middleware
+// chain, which runs after the global chain and applies only to this logger
+func WithContextOutputMiddleware(middlewares ...OutputMiddleware) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		if l.outputMiddleware == nil {
+			l.outputMiddleware = NewOutputMiddlewareChain()
+		}
+		for _, middleware := range middlewares {
+			l.outputMiddleware.Add(middleware)
+		}
+	}
 }
 
-// NewContextLogger creates a new ContextLogger instance
+// WithContextGlobalMiddleware enables or disables the global middleware chains for the logger
+// When disabled, only the logger's own chains are applied. Enabled by default
+func WithContextGlobalMiddleware(enabled bool) ContextLoggerOption {
+	return func(l *ContextLogger) {
+		l.skipGlobalMiddleware = !enabled
+	}
+}
+
+// NewContextLogger creates a new ContextLogger instance configured from the global configuration
+// (Output, MinLevel, PrettifyJSON, MaxLogEntries, line limit, Sinks and Sampler)
 func NewContextLogger() *ContextLogger {
-	// Get global config to determine formatter settings
+	// Get global config to determine output, level and formatter settings
 	config := GetConfig()
 
 	base := newBaseLogger()
-	base.output = os.Stdout // Set default output for ContextLogger
+	base.output = config.Output
 	base.minLevel = config.MinLevel
+	base.sink = newSinkFromConfig(config.Sinks)
 
 	return &ContextLogger{
 		BaseLogger: &base,
@@ -32,57 +156,85 @@ func NewContextLogger() *ContextLogger {
 		fields:     make(map[string]interface{}),
 		startTime:  time.Now(),
 		maxEntries: config.MaxLogEntries,
+		headLines:  config.HeadLines,
+		tailLines:  config.TailLines,
+		sampler:    config.Sampler,
+	}
+}
+
+// NewContextLoggerWithOptions creates a new ContextLogger from the global configuration,
+// overridden by the given options
+//
+// Usage:
+//
+//	contextLogger := logger.NewContextLoggerWithOptions(
+//	    logger.WithContextOutput(jobLog),
+//	    logger.WithContextLogType("batch"),
+//	    logger.WithContextFields(map[string]interface{}{"job_id": jobID}),
+//	)
+func NewContextLoggerWithOptions(options ...ContextLoggerOption) *ContextLogger {
+	l := NewContextLogger()
+	for _, option := range options {
+		option(l)
 	}
+	return l
 }
 
 // addEntry adds a log entry to the context logger
-func (l *ContextLogger) addEntry(level LogLevel, message string) {
+// span is the active span the entry belongs to, or nil
+func (l *ContextLogger) addEntry(level LogLevel, message string, fields map[string]interface{}, span *Span) {
 	if !l.isLevelEnabled(level) {
 		return
 	}
 
+	// Add source information if enabled
+	var sourceInfo *SourceInfo
+	config := GetConfig()
+	if config.EnableSourceInfo {
+		// Skip levels: getSourceInfo(0) -> addEntry(1) -> Infof/Infow or context.go helper(2) -> actual caller(3)
+		sourceInfo = getSourceInfo(3)
+	}
+
+	l.appendEntry(level, message, fields, span, sourceInfo)
+}
+
+// appendEntry builds a log entry with the given source information and passes it
+// through the middleware chain before storing it. Level filtering is the caller's job.
+func (l *ContextLogger) appendEntry(level LogLevel, message string, fields map[string]interface{}, span *Span, sourceInfo *SourceInfo) {
+	maxMessageLength := GetConfig().MaxMessageLength
+
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
+	// Do nothing if there is neither output nor sink, as the entry could never be flushed
+	if !l.hasDestination() {
+		return
+	}
+
 	// Get LogEntry from pool instead of creating new one
 	entry := getLogEntry()
 	entry.Timestamp = time.Now()
 	entry.Level = level.String()
 	entry.Message = message
+	entry.Fields = fields
+	if span != nil {
+		entry.SpanID = span.id
+	}
 
-	// Add source information if enabled
-	config := GetConfig()
-	if config.EnableSourceInfo {
-		// Determine the appropriate skip level by examining the call stack
-		skipLevel := 3 // Default for direct ContextLogger method calls
-
-		// Check the call stack to see if we're being called through context.go functions
-		for i := 2; i <= 5; i++ {
-			if pc, _, _, ok := runtime.Caller(i); ok {
-				if fn := runtime.FuncForPC(pc); fn != nil {
-					funcName := fn.Name()
-					// If we find a context.go function in the stack, use skip level 4
-					if strings.Contains(funcName, "/logger.Infof") ||
-						strings.Contains(funcName, "/logger.Debugf") ||
-						strings.Contains(funcName, "/logger.Warnf") ||
-						strings.Contains(funcName, "/logger.Errorf") ||
-						strings.Contains(funcName, "/logger.Criticalf") {
-						skipLevel = 4
-						break
-					}
-				}
-			}
-		}
-
-		sourceInfo := getSourceInfo(skipLevel)
+	if sourceInfo != nil {
 		entry.Funcname = sourceInfo.Funcname
 		entry.Filename = sourceInfo.Filename
 		entry.Fileline = sourceInfo.Fileline
 	}
 
-	// Process through global middleware chain
-	processWithGlobalMiddleware(entry, func(processedEntry *LogEntry) {
+	// Process through the global and the logger's middleware chains
+	l.processEntry(entry, func(processedEntry *LogEntry) {
+		truncateMessage(processedEntry, maxMessageLength)
+		if span != nil {
+			span.recordLevel(ParseLogLevel(processedEntry.Level))
+		}
 		l.entries = append(l.entries, processedEntry)
+		l.enforceLineLimit()
 
 		// Check if we need to auto-flush due to entry limit
 		if l.maxEntries > 0 && len(l.entries) >= l.maxEntries {
@@ -94,7 +246,7 @@ func (l *ContextLogger) addEntry(level LogLevel, message string) {
 // flushInternal performs the flush operation without acquiring the mutex
 // This method assumes the mutex is already held by the caller
 func (l *ContextLogger) flushInternal() {
-	if l.output == nil {
+	if !l.hasDestination() {
 		return
 	}
 
@@ -108,26 +260,23 @@ func (l *ContextLogger) flushInternal() {
 
 	endTime := time.Now()
 
-	// Use the formatter (default or explicitly set)
-	jsonData, err := formatLogOutput(l.entries, l.fields, l.startTime, endTime, l.formatter)
-	if err != nil {
-		// Handle formatting error using error handler
-		handleError("format", err)
-		// Fallback to simple output if formatting fails
-		_, writeErr := fmt.Fprintf(l.output, "Error formatting log: %v\n", err)
-		if writeErr != nil {
-			handleError("write_fallback", writeErr)
-		}
-		return
+	logOutput := newLogOutput(l.entries, l.fields, l.startTime, endTime)
+	logOutput.Runtime.Spans = l.spanTree(endTime)
+	if l.logType != "" {
+		logOutput.Type = l.logType
+	}
+	if l.severity > ParseLogLevel(logOutput.Runtime.Severity) {
+		logOutput.Runtime.Severity = l.severity.String()
+	}
+	if l.droppedLines > 0 {
+		logOutput.Runtime.Truncated = true
+		logOutput.Runtime.DroppedLines = l.droppedLines
 	}
 
-	if _, err := fmt.Fprintf(l.output, "%s\n", jsonData); err != nil {
-		// Handle write error using error handler
-		handleError("write", err)
-		// Try to write an error message as fallback
-		_, fallbackErr := fmt.Fprintf(l.output, "Error writing log output: %v\n", err)
-		if fallbackErr != nil {
-			handleError("write_error_fallback", fallbackErr)
+	// Sampled-out aggregates are discarded like written ones
+	if l.sampler == nil || l.sampler.Sample(logOutput) {
+		if !l.writeLogOutput(logOutput) {
+			return
 		}
 	}
 
@@ -139,6 +288,9 @@ func (l *ContextLogger) flushInternal() {
 	// Clear entries after flushing and reset start time
 	l.entries = l.entries[:0] // Clear slice but keep capacity
 	l.startTime = time.Now()  // Reset start time for next batch
+	l.severity = DebugLevel   // Reset the severity floor for next batch
+	l.droppedLines = 0
+	l.pruneSpans()
 }
 
 // Flush outputs all accumulated log entries as a single JSON
@@ -164,27 +316,61 @@ func (l *ContextLogger) AddContextValues(fields map[string]interface{}) {
 	}
 }
 
+// RaiseSeverity raises the aggregate severity (runtime.severity) of the next flush to
+// at least the given level, even if no line was logged at that level
+// Useful when the outcome of an operation, such as an HTTP status code, is known only at the end
+func (l *ContextLogger) RaiseSeverity(level LogLevel) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.severity = GetHigherLevel(l.severity, level)
+}
+
 // Debugf logs a debug message
 func (l *ContextLogger) Debugf(format string, args ...interface{}) {
-	l.addEntry(DebugLevel, fmt.Sprintf(format, args...))
+	l.addEntry(DebugLevel, fmt.Sprintf(format, args...), nil, nil)
 }
 
 // Infof logs an info message
 func (l *ContextLogger) Infof(format string, args ...interface{}) {
-	l.addEntry(InfoLevel, fmt.Sprintf(format, args...))
+	l.addEntry(InfoLevel, fmt.Sprintf(format, args...), nil, nil)
 }
 
 // Warnf logs a warning message
 func (l *ContextLogger) Warnf(format string, args ...interface{}) {
-	l.addEntry(WarnLevel, fmt.Sprintf(format, args...))
+	l.addEntry(WarnLevel, fmt.Sprintf(format, args...), nil, nil)
 }
 
 // Errorf logs an error message
 func (l *ContextLogger) Errorf(format string, args ...interface{}) {
-	l.addEntry(ErrorLevel, fmt.Sprintf(format, args...))
+	l.addEntry(ErrorLevel, fmt.Sprintf(format, args...), nil, nil)
 }
 
 // Criticalf logs a critical message
 func (l *ContextLogger) Criticalf(format string, args ...interface{}) {
-	l.addEntry(CriticalLevel, fmt.Sprintf(format, args...))
+	l.addEntry(CriticalLevel, fmt.Sprintf(format, args...), nil, nil)
+}
+
+// Debugw logs a debug message with structured key/value fields attached to the line
+func (l *ContextLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.addEntry(DebugLevel, msg, fieldsFromKeysAndValues(keysAndValues), nil)
+}
+
+// Infow logs an info message with structured key/value fields attached to the line
+func (l *ContextLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.addEntry(InfoLevel, msg, fieldsFromKeysAndValues(keysAndValues), nil)
+}
+
+// Warnw logs a warning message with structured key/value fields attached to the line
+func (l *ContextLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.addEntry(WarnLevel, msg, fieldsFromKeysAndValues(keysAndValues), nil)
+}
+
+// Errorw logs an error message with structured key/value fields attached to the line
+func (l *ContextLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.addEntry(ErrorLevel, msg, fieldsFromKeysAndValues(keysAndValues), nil)
+}
+
+// Criticalw logs a critical message with structured key/value fields attached to the line
+func (l *ContextLogger) Criticalw(msg string, keysAndValues ...interface{}) {
+	l.addEntry(CriticalLevel, msg, fieldsFromKeysAndValues(keysAndValues), nil)
 }
@@ -3,8 +3,11 @@ package logger
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"strings"
 	"testing"
+
+	"github.com/zentooo/logspan/formatter"
 )
 
 func TestContextLogger_BasicLogging(t *testing.T) {
@@ -163,6 +166,11 @@ func TestContextLogger_NilOutput(t *testing.T) {
 	// Should not panic
 	logger.Infof("Test message")
 	logger.Flush()
+
+	// Entries are not kept, as they could never be written
+	if len(logger.entries) != 0 {
+		t.Errorf("Expected no entries to be kept without an output, got %d", len(logger.entries))
+	}
 }
 
 func TestContextLogger_SeverityCalculation(t *testing.T) {
@@ -426,3 +434,174 @@ func TestContextLogger_AutoFlushWithLevelFiltering(t *testing.T) {
 		t.Error("Expected 'Error message 1' in output")
 	}
 }
+
+func TestContextLogger_StructuredFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewContextLogger()
+	logger.SetOutput(&buf)
+	logger.SetLevel(DebugLevel)
+
+	logger.Debugw("cache lookup", "cache_hit", false)
+	logger.Infow("query executed", "db_rows", 12, "table", "users")
+	logger.Warnw("slow query", "elapsed_ms", 250)
+	logger.Errorw("query failed", "retryable", true)
+	logger.Criticalw("database down")
+	logger.Flush()
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	lines := output["runtime"].(map[string]interface{})["lines"].([]interface{})
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 lines, got %d", len(lines))
+	}
+
+	queryLine := lines[1].(map[string]interface{})
+	if queryLine["message"] != "query executed" {
+		t.Errorf("Expected message 'query executed', got %v", queryLine["message"])
+	}
+	fields, ok := queryLine["fields"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected fields object on line")
+	}
+	if fields["db_rows"] != float64(12) || fields["table"] != "users" {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+
+	if _, exists := lines[4].(map[string]interface{})["fields"]; exists {
+		t.Error("Expected fields to be omitted when no key/value pairs are given")
+	}
+	if output["runtime"].(map[string]interface{})["severity"] != "CRITICAL" {
+		t.Errorf("Expected severity CRITICAL, got %v", output["runtime"].(map[string]interface{})["severity"])
+	}
+}
+
+func TestContextLogger_RaiseSeverity(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewContextLogger()
+	logger.SetOutput(&buf)
+
+	logger.Infof("handled")
+	logger.RaiseSeverity(WarnLevel)
+	logger.RaiseSeverity(InfoLevel) // Lower levels do not lower the floor
+
+	output := flushAndDecode(t, logger, &buf)
+	if output.Runtime.Severity != "WARN" {
+		t.Errorf("Expected raised severity WARN, got %s", output.Runtime.Severity)
+	}
+
+	// Lines above the floor still win
+	buf.Reset()
+	logger.RaiseSeverity(WarnLevel)
+	logger.Errorf("failed")
+	output = flushAndDecode(t, logger, &buf)
+	if output.Runtime.Severity != "ERROR" {
+		t.Errorf("Expected line severity ERROR, got %s", output.Runtime.Severity)
+	}
+
+	// The floor is reset after each flush
+	buf.Reset()
+	logger.Infof("next batch")
+	output = flushAndDecode(t, logger, &buf)
+	if output.Runtime.Severity != "INFO" {
+		t.Errorf("Expected severity to be reset to INFO, got %s", output.Runtime.Severity)
+	}
+}
+
+func TestNewContextLogger_UsesGlobalConfig(t *testing.T) {
+	var buf bytes.Buffer
+	Init(WithOutput(&buf), WithPrettifyJSON(true), WithMinLevel(WarnLevel))
+	defer Init()
+
+	logger := NewContextLogger()
+	logger.Infof("filtered")
+	logger.Warnf("kept")
+	logger.Flush()
+
+	output := buf.String()
+	if !strings.Contains(output, "kept") || strings.Contains(output, "filtered") {
+		t.Errorf("Expected the global output and level to be used, got %q", output)
+	}
+	if !strings.Contains(output, "\n  ") {
+		t.Errorf("Expected prettified JSON from the global config, got %q", output)
+	}
+}
+
+func TestNewContextLoggerWithOptions(t *testing.T) {
+	var global, own bytes.Buffer
+	Init(WithOutput(&global), WithMaxLogEntries(0))
+	defer Init()
+
+	logger := NewContextLoggerWithOptions(
+		WithContextOutput(&own),
+		WithContextFormatter(formatter.NewContextFlattenFormatter()),
+		WithContextMinLevel(DebugLevel),
+		WithContextLogType("batch"),
+		WithContextMaxLogEntries(2),
+		WithContextFields(map[string]interface{}{"job_id": "job-1"}),
+	)
+
+	logger.Debugf("first")
+	logger.Debugf("second") // Reaches the per-logger auto-flush threshold
+
+	if global.Len() != 0 {
+		t.Errorf("Expected nothing on the global output, got %q", global.String())
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(own.Bytes(), &result); err != nil {
+		t.Fatalf("Expected an auto-flushed document, got %v: %q", err, own.String())
+	}
+	if result["type"] != "batch" {
+		t.Errorf("Expected type batch, got %v", result["type"])
+	}
+	if result["job_id"] != "job-1" {
+		t.Errorf("Expected flattened initial field job_id, got %v", result)
+	}
+	if lines := result["runtime"].(map[string]interface{})["lines"].([]interface{}); len(lines) != 2 {
+		t.Errorf("Expected 2 DEBUG lines, got %d", len(lines))
+	}
+}
+
+func TestNewContextLoggerWithOptions_Middleware(t *testing.T) {
+	ClearMiddleware()
+	ClearOutputMiddleware()
+	defer ClearMiddleware()
+	defer ClearOutputMiddleware()
+
+	AddMiddleware(func(entry *LogEntry, next func(*LogEntry)) {
+		entry.Message = "[global] " + entry.Message
+		next(entry)
+	})
+
+	auditMasker := NewPasswordMaskingMiddleware().WithMaskString("[AUDIT]")
+	var auditBuf, appBuf bytes.Buffer
+	auditLogger := NewContextLoggerWithOptions(
+		WithContextOutput(&auditBuf),
+		WithContextMiddleware(auditMasker.Middleware()),
+		WithContextOutputMiddleware(EnrichContext(func(output *formatter.LogOutput) map[string]interface{} {
+			return map[string]interface{}{"token": "[AUDIT]"}
+		})),
+		WithContextGlobalMiddleware(false),
+	)
+	appLogger := NewContextLoggerWithOptions(WithContextOutput(&appBuf))
+
+	auditLogger.AddContextValue("token", "abc")
+	auditLogger.Infof("password=secret")
+	auditLogger.Flush()
+	appLogger.AddContextValue("token", "abc")
+	appLogger.Infof("password=secret")
+	appLogger.Flush()
+
+	audit := decodeOutput(t, &auditBuf)
+	if audit.Runtime.Lines[0].Message != "password=[AUDIT]" || audit.Context["token"] != "[AUDIT]" {
+		t.Errorf("Expected audit logger to use only its own chains, got %+v", audit)
+	}
+
+	app := decodeOutput(t, &appBuf)
+	if app.Runtime.Lines[0].Message != "[global] password=secret" || app.Context["token"] != "abc" {
+		t.Errorf("Expected app logger to use only the global chain, got %+v", app)
+	}
+}
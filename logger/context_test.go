@@ -219,3 +219,51 @@ func TestAddContextValues_NilMap(t *testing.T) {
 		t.Errorf("Expected no context fields, got %d", len(contextLogger.fields))
 	}
 }
+
+func TestContextAPI_StructuredFields(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	contextLogger.SetLevel(DebugLevel)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	Debugw(ctx, "debug line", "step", 1)
+	Infow(ctx, "info line", "step", 2)
+	Warnw(ctx, "warn line", "step", 3)
+	Errorw(ctx, "error line", "step", 4)
+	Criticalw(ctx, "critical line", "step", 5)
+	FlushContext(ctx)
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	lines := output["runtime"].(map[string]interface{})["lines"].([]interface{})
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		fields := line.(map[string]interface{})["fields"].(map[string]interface{})
+		if fields["step"] != float64(i+1) {
+			t.Errorf("Line %d: expected step=%d, got %v", i, i+1, fields["step"])
+		}
+	}
+}
+
+func TestContextAPI_StructuredFieldsSourceInfo(t *testing.T) {
+	Init(WithSourceInfo(true))
+	defer Init()
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	Infow(ctx, "with source", "key", "value")
+	FlushContext(ctx)
+
+	if !strings.Contains(buf.String(), "context_test.go") {
+		t.Errorf("Expected source info to point at the caller, got %s", buf.String())
+	}
+}
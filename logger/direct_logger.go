@@ -9,6 +9,7 @@ import (
 // DirectLogger implements the Logger interface for direct logging without context
 type DirectLogger struct {
 	*BaseLogger
+	limiter *rateLimiter // Limits messages per template, see SetRateLimit
 }
 
 // NewDirectLogger creates a new DirectLogger instance
@@ -20,17 +21,93 @@ func NewDirectLogger() *DirectLogger {
 	}
 }
 
+// SetRateLimit limits the logger to burst messages per message template in each interval
+// The template is the format string of the *f methods and the message of the *w methods, so
+// messages that differ only in their arguments share a limit. Suppressed messages are counted,
+// and at the end of the interval a single "suppressed N similar messages" line is written at
+// the highest suppressed level, with the template and the count as fields.
+// A burst or interval of 0 disables the limit.
+//
+// Usage:
+//
+//	logger.D.(*logger.DirectLogger).SetRateLimit(10, time.Minute)
+func (l *DirectLogger) SetRateLimit(burst int, interval time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if burst <= 0 || interval <= 0 {
+		l.limiter = nil
+		return
+	}
+	l.limiter = newRateLimiter(burst, interval, l.writeSummary)
+}
+
+// allow reports whether a message with the template passes the rate limit
+func (l *DirectLogger) allow(level LogLevel, template string) bool {
+	l.mutex.Lock()
+	limiter := l.limiter
+	l.mutex.Unlock()
+	return limiter == nil || limiter.allow(template, level)
+}
+
+// enabled reports whether an entry at the level should be written, applying the level
+// filter and then the rate limit to the message template
+func (l *DirectLogger) enabled(level LogLevel, template string) bool {
+	return l.isLevelEnabled(level) && l.allow(level, template)
+}
+
+// writeSummary writes the line reporting messages suppressed by the rate limit
+func (l *DirectLogger) writeSummary(level LogLevel, template string, suppressed int) {
+	l.writeEntry(level, fmt.Sprintf("suppressed %d similar messages", suppressed), map[string]interface{}{
+		"template":   template,
+		"suppressed": suppressed,
+	}, nil)
+}
+
 // logf writes a log entry with the given level and message in structured format
 func (l *DirectLogger) logf(level LogLevel, format string, args ...interface{}) {
-	if !l.isLevelEnabled(level) {
+	if !l.enabled(level, format) {
+		return
+	}
+
+	// Add source information if enabled
+	var sourceInfo *SourceInfo
+	config := GetConfig()
+	if config.EnableSourceInfo {
+		// Skip levels: getSourceInfo(0) -> logf(1) -> Infof/Debugf/etc(2) -> actual caller(3)
+		sourceInfo = getSourceInfo(3)
+	}
+
+	l.writeEntry(level, fmt.Sprintf(format, args...), nil, sourceInfo)
+}
+
+// logw writes a log entry with the given level, message and structured fields
+func (l *DirectLogger) logw(level LogLevel, msg string, keysAndValues ...interface{}) {
+	if !l.enabled(level, msg) {
 		return
 	}
 
+	// Add source information if enabled
+	var sourceInfo *SourceInfo
+	config := GetConfig()
+	if config.EnableSourceInfo {
+		// Skip levels: getSourceInfo(0) -> logw(1) -> Infow/Debugw/etc(2) -> actual caller(3)
+		sourceInfo = getSourceInfo(3)
+	}
+
+	l.writeEntry(level, msg, fieldsFromKeysAndValues(keysAndValues), sourceInfo)
+}
+
+// writeEntry builds a log entry with the given source information, passes it through
+// the middleware chain and writes it immediately. Level filtering is the caller's job.
+func (l *DirectLogger) writeEntry(level LogLevel, message string, fields map[string]interface{}, sourceInfo *SourceInfo) {
+	maxMessageLength := GetConfig().MaxMessageLength
+
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	// Do nothing if output is nil
-	if l.output == nil {
+	// Do nothing if there is neither output nor sink
+	if !l.hasDestination() {
 		return
 	}
 
@@ -40,47 +117,24 @@ func (l *DirectLogger) logf(level LogLevel, format string, args ...interface{})
 	entry := getLogEntry()
 	entry.Timestamp = now
 	entry.Level = level.String()
-	entry.Message = fmt.Sprintf(format, args...)
+	entry.Message = message
+	entry.Fields = fields
 
-	// Add source information if enabled
-	config := GetConfig()
-	if config.EnableSourceInfo {
-		// Skip levels: getSourceInfo(0) -> logf(1) -> Infof/Debugf/etc(2) -> actual caller(3)
-		sourceInfo := getSourceInfo(3)
+	if sourceInfo != nil {
 		entry.Funcname = sourceInfo.Funcname
 		entry.Filename = sourceInfo.Filename
 		entry.Fileline = sourceInfo.Fileline
 	}
 
-	// Process through global middleware chain
-	processWithGlobalMiddleware(entry, func(processedEntry *LogEntry) {
+	// Process through the global and the logger's middleware chains
+	l.processEntry(entry, func(processedEntry *LogEntry) {
+		truncateMessage(processedEntry, maxMessageLength)
+
 		// Create a temporary slice for single entry processing
 		entries := []*LogEntry{processedEntry}
 
 		// Format and output the log entry
-		jsonData, err := formatLogOutput(entries, nil, now, now, l.formatter)
-		if err != nil {
-			// Handle formatting error using error handler
-			handleError("format", err)
-			// Fallback to simple output if formatting fails
-			_, writeErr := fmt.Fprintf(l.output, "Error formatting log: %v\n", err)
-			if writeErr != nil {
-				handleError("write_fallback", writeErr)
-			}
-			// Return entry to pool even on error
-			putLogEntry(processedEntry)
-			return
-		}
-
-		if _, err := fmt.Fprintf(l.output, "%s\n", jsonData); err != nil {
-			// Handle write error using error handler
-			handleError("write", err)
-			// Try to write an error message as fallback
-			_, fallbackErr := fmt.Fprintf(l.output, "Error writing log output: %v\n", err)
-			if fallbackErr != nil {
-				handleError("write_error_fallback", fallbackErr)
-			}
-		}
+		l.writeLogOutput(newLogOutput(entries, nil, now, now))
 
 		// Return entry to pool after processing
 		putLogEntry(processedEntry)
@@ -111,3 +165,28 @@ func (l *DirectLogger) Errorf(format string, args ...interface{}) {
 func (l *DirectLogger) Criticalf(format string, args ...interface{}) {
 	l.logf(CriticalLevel, format, args...)
 }
+
+// Debugw logs a debug message with structured key/value fields
+func (l *DirectLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.logw(DebugLevel, msg, keysAndValues...)
+}
+
+// Infow logs an info message with structured key/value fields
+func (l *DirectLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.logw(InfoLevel, msg, keysAndValues...)
+}
+
+// Warnw logs a warning message with structured key/value fields
+func (l *DirectLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.logw(WarnLevel, msg, keysAndValues...)
+}
+
+// Errorw logs an error message with structured key/value fields
+func (l *DirectLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.logw(ErrorLevel, msg, keysAndValues...)
+}
+
+// Criticalw logs a critical message with structured key/value fields
+func (l *DirectLogger) Criticalw(msg string, keysAndValues ...interface{}) {
+	l.logw(CriticalLevel, msg, keysAndValues...)
+}
@@ -464,3 +464,36 @@ func TestDirectLogger_StructuredOutput(t *testing.T) {
 		t.Errorf("Expected exactly 1 log entry, got %d", len(lines))
 	}
 }
+
+func TestDirectLogger_StructuredFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDirectLogger()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(formatter.NewJSONFormatter())
+
+	logger.Infow("cache lookup", "cache_hit", true, "key", "user:1")
+
+	var logData map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &logData); err != nil {
+		t.Fatalf("Expected valid JSON output, got error: %v", err)
+	}
+
+	lines := logData["runtime"].(map[string]interface{})["lines"].([]interface{})
+	line := lines[0].(map[string]interface{})
+	if line["message"] != "cache lookup" {
+		t.Errorf("Expected message 'cache lookup', got %v", line["message"])
+	}
+	fields, ok := line["fields"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected fields object on line")
+	}
+	if fields["cache_hit"] != true || fields["key"] != "user:1" {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+
+	buf.Reset()
+	logger.Debugw("filtered", "a", 1)
+	if buf.Len() != 0 {
+		t.Error("Debugw should be filtered at INFO level")
+	}
+}
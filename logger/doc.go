@@ -64,9 +64,9 @@
 //
 // The logger supports a middleware system for processing log entries:
 //
-//	// Add password masking middleware
+//	// Add password masking middleware for lines and, at flush time, context values
 //	passwordMasker := logger.NewPasswordMaskingMiddleware()
-//	logger.AddMiddleware(passwordMasker.Middleware())
+//	logger.AddPasswordMasking(passwordMasker)
 //
 //	// Add custom middleware
 //	logger.AddMiddleware(func(entry *logger.LogEntry, next func(*logger.LogEntry)) {
@@ -75,6 +75,26 @@
 //	    next(entry)
 //	})
 //
+// PII detectors extend the masker to personal data found by value, such as emails, card
+// numbers and IP addresses, each replaced in full, partially, by a salted hash, or dropped:
+//
+//	passwordMasker.WithDetectors(logger.DefaultPIIDetectors()...).WithHashSalt(salt)
+//
+// Output middleware processes the whole document when it is written, after all context values
+// are known. Built-in output middleware filters lines with the aggregate in view, adds computed
+// context, or vetoes a flush:
+//
+//	logger.AddOutputMiddleware(logger.CountLinesByLevel(""))
+//	logger.AddOutputMiddleware(logger.DebugLinesOnError())
+//
+// Each logger can also carry its own chains, run after the global chains or instead of them:
+//
+//	auditLogger := logger.NewContextLoggerWithOptions(
+//	    logger.WithContextMiddleware(auditMasker.Middleware()),
+//	    logger.WithContextOutputMiddleware(auditMasker.OutputMiddleware()),
+//	    logger.WithContextGlobalMiddleware(false),
+//	)
+//
 // # HTTP Integration
 //
 // Automatic HTTP request logging with context setup:
@@ -93,6 +113,9 @@
 //   - EnableSourceInfo: Enable source file information in logs
 //   - PrettifyJSON: Enable pretty-printed JSON output
 //   - MaxLogEntries: Maximum log entries before auto-flush (0 = no limit)
+//   - MaxMessageLength, HeadLines/TailLines, MaxOutputBytes: Size limits (0 = no limit)
+//   - Sampler: Tail-based sampling of context logger flushes (nil = write everything)
+//   - RateLimitBurst, RateLimitInterval: Per-template rate limit of D (0 = no limit)
 //
 // # Log Levels
 //
@@ -114,6 +137,30 @@
 // When the number of accumulated log entries reaches MaxLogEntries,
 // the logger automatically flushes the entries and continues accumulating new ones.
 //
+// Size limits keep a single document per flush instead:
+//
+//	logger.Init(
+//	    logger.WithMaxMessageLength(4096),   // Truncate long messages ("truncated": true)
+//	    logger.WithLineLimit(50, 200),       // Keep the first 50 and the last 200 lines
+//	    logger.WithMaxOutputBytes(256*1024), // Drop lines until the document fits
+//	)
+//
+// Dropped lines are reported with "truncated": true and "droppedLines" in the runtime section.
+//
+// A Sampler decides at flush time which aggregates are written, keeping every error:
+//
+//	logger.Init(logger.WithSampler(logger.NewSampler(
+//	    logger.WithSampleSlowerThan(500*time.Millisecond), // Keep slow requests
+//	    logger.WithSampleRate(0.01),                       // And 1% of the other non-errors
+//	)))
+//
+// Sampled-out aggregates are counted, see GetSamplingStats.
+//
+// The global DirectLogger can be rate limited per message template, so that a tight error loop
+// writes a few lines and then a single "suppressed N similar messages" summary per interval:
+//
+//	logger.Init(logger.WithRateLimit(10, time.Minute))
+//
 // # Thread Safety
 //
 // All logger operations are thread-safe and can be used concurrently
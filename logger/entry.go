@@ -14,6 +14,15 @@ type LogEntry struct {
 	Funcname  string    `json:"funcname,omitempty"`
 	Filename  string    `json:"filename,omitempty"`
 	Fileline  int       `json:"fileline,omitempty"`
+
+	// Fields holds structured key/value data attached to this entry only
+	Fields map[string]interface{} `json:"fields,omitempty"`
+
+	// SpanID is the identifier of the span that was active when the entry was logged
+	SpanID string `json:"spanId,omitempty"`
+
+	// Truncated is set when the message was shortened to the maximum message length
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // SourceInfo holds source code location information
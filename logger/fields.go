@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BadKey is the field key used for values that are not preceded by a string key
+// in a key/value list, such as a trailing value without a key
+const BadKey = "!BADKEY"
+
+// fieldsFromKeysAndValues converts an alternating key/value list into a field map
+// Keys must be strings; a non-string key or a key without a value is stored under BadKey
+// Returns nil when the list is empty
+func fieldsFromKeysAndValues(keysAndValues []interface{}) map[string]interface{} {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			fields[BadKey] = keysAndValues[i]
+			i++
+			continue
+		}
+
+		if i+1 >= len(keysAndValues) {
+			fields[BadKey] = key
+			break
+		}
+
+		fields[key] = keysAndValues[i+1]
+		i += 2
+	}
+	return fields
+}
+
+// appendFields appends the key/value list to the message as key=value pairs in key order
+func appendFields(msg string, keysAndValues []interface{}) string {
+	fields := fieldsFromKeysAndValues(keysAndValues)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, fields[key])
+	}
+	return b.String()
+}
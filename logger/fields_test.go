@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldsFromKeysAndValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "empty list",
+			input:    nil,
+			expected: nil,
+		},
+		{
+			name:     "key/value pairs",
+			input:    []interface{}{"db_rows", 12, "cache_hit", true},
+			expected: map[string]interface{}{"db_rows": 12, "cache_hit": true},
+		},
+		{
+			name:     "trailing key without value",
+			input:    []interface{}{"db_rows", 12, "dangling"},
+			expected: map[string]interface{}{"db_rows": 12, BadKey: "dangling"},
+		},
+		{
+			name:     "non-string key",
+			input:    []interface{}{42, "user", "alice"},
+			expected: map[string]interface{}{BadKey: 42, "user": "alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldsFromKeysAndValues(tt.input)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("fieldsFromKeysAndValues(%v) = %v, expected %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io"
 	"time"
 
 	"github.com/zentooo/logspan/formatter"
@@ -18,6 +19,11 @@ func createDefaultFormatter() formatter.Formatter {
 // formatLogOutput creates a LogOutput structure and formats it using the given formatter
 // If formatter is nil, uses default JSONFormatter
 func formatLogOutput(entries []*LogEntry, contextFields map[string]interface{}, startTime, endTime time.Time, f formatter.Formatter) ([]byte, error) {
+	return formatOutput(newLogOutput(entries, contextFields, startTime, endTime), f)
+}
+
+// newLogOutput creates a LogOutput structure from the accumulated entries and context fields
+func newLogOutput(entries []*LogEntry, contextFields map[string]interface{}, startTime, endTime time.Time) *formatter.LogOutput {
 	elapsed := endTime.Sub(startTime).Milliseconds()
 
 	// Find the highest severity level
@@ -37,6 +43,9 @@ func formatLogOutput(entries []*LogEntry, contextFields map[string]interface{},
 			Funcname:  entry.Funcname,
 			Filename:  entry.Filename,
 			Fileline:  entry.Fileline,
+			Fields:    entry.Fields,
+			SpanID:    entry.SpanID,
+			Truncated: entry.Truncated,
 		}
 	}
 
@@ -48,7 +57,7 @@ func formatLogOutput(entries []*LogEntry, contextFields map[string]interface{},
 	}
 
 	// Create LogOutput structure
-	logOutput := &formatter.LogOutput{
+	return &formatter.LogOutput{
 		Type:    logType,
 		Context: contextFields,
 		Runtime: formatter.RuntimeInfo{
@@ -59,7 +68,11 @@ func formatLogOutput(entries []*LogEntry, contextFields map[string]interface{},
 			Lines:     formatterEntries,
 		},
 	}
+}
 
+// formatOutput formats the LogOutput using the given formatter
+// If formatter is nil, uses default JSONFormatter
+func formatOutput(logOutput *formatter.LogOutput, f formatter.Formatter) ([]byte, error) {
 	// Use provided formatter or default JSONFormatter
 	if f == nil {
 		// Use default JSONFormatter with prettify setting from global config
@@ -68,3 +81,29 @@ func formatLogOutput(entries []*LogEntry, contextFields map[string]interface{},
 
 	return f.Format(logOutput)
 }
+
+// formatRecords formats the LogOutput as one or more records using the given formatter
+// Formatters that implement formatter.RecordFormatter may split the output into several
+// records; any other formatter produces a single record with the aggregated severity.
+// If formatter is nil, uses default JSONFormatter
+func formatRecords(logOutput *formatter.LogOutput, f formatter.Formatter) ([]formatter.Record, error) {
+	if rf, ok := f.(formatter.RecordFormatter); ok {
+		return rf.FormatRecords(logOutput)
+	}
+
+	data, err := formatOutput(logOutput, f)
+	if err != nil {
+		return nil, err
+	}
+	return []formatter.Record{{Level: logOutput.Runtime.Severity, Data: data}}, nil
+}
+
+// writeRecords writes each record as a separate document, stopping at the first error
+func writeRecords(w io.Writer, records []formatter.Record) error {
+	for _, record := range records {
+		if err := writeOutput(w, ParseLogLevel(record.Level), record.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package logger
+
+import "io"
+
+// LevelWriter is an output that receives the severity of each write
+// When the output of a logger implements LevelWriter, WriteLevel is called instead of Write
+// with the aggregated severity of a ContextLogger flush or the level of a DirectLogger line.
+// Outputs can use it to filter or prioritize documents, for example when a queue is full.
+type LevelWriter interface {
+	io.Writer
+
+	// WriteLevel writes p, which holds one complete log document, with its severity
+	WriteLevel(level LogLevel, p []byte) (n int, err error)
+}
+
+// writeOutput writes one formatted document followed by a newline in a single call,
+// passing the severity to outputs that implement LevelWriter
+func writeOutput(w io.Writer, level LogLevel, data []byte) error {
+	line := make([]byte, 0, len(data)+1)
+	line = append(line, data...)
+	line = append(line, '\n')
+
+	if lw, ok := w.(LevelWriter); ok {
+		_, err := lw.WriteLevel(level, line)
+		return err
+	}
+	_, err := w.Write(line)
+	return err
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// levelRecorder records the levels passed to WriteLevel
+type levelRecorder struct {
+	bytes.Buffer
+	levels []LogLevel
+}
+
+func (r *levelRecorder) WriteLevel(level LogLevel, p []byte) (int, error) {
+	r.levels = append(r.levels, level)
+	return r.Write(p)
+}
+
+func TestLevelWriter_ContextLoggerPassesAggregateSeverity(t *testing.T) {
+	out := &levelRecorder{}
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(out)
+
+	contextLogger.Infof("info")
+	contextLogger.Warnf("warn")
+	contextLogger.Flush()
+
+	if len(out.levels) != 1 || out.levels[0] != WarnLevel {
+		t.Errorf("Expected a single WriteLevel call with WARN, got %v", out.levels)
+	}
+	if out.Len() == 0 || out.Bytes()[out.Len()-1] != '\n' {
+		t.Errorf("Expected a newline-terminated document, got %q", out.String())
+	}
+}
+
+func TestLevelWriter_DirectLoggerPassesLineLevel(t *testing.T) {
+	out := &levelRecorder{}
+	directLogger := NewDirectLogger()
+	directLogger.SetOutput(out)
+
+	directLogger.Errorf("failed")
+	directLogger.Infof("done")
+
+	if len(out.levels) != 2 || out.levels[0] != ErrorLevel || out.levels[1] != InfoLevel {
+		t.Errorf("Expected ERROR and INFO writes, got %v", out.levels)
+	}
+}
+
+func TestLevelWriter_RecordFormatterWritesEachRecord(t *testing.T) {
+	out := &levelRecorder{}
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(out)
+	contextLogger.SetFormatter(formatter.NewExplodedJSONFormatter())
+
+	contextLogger.Infof("info")
+	contextLogger.Warnf("warn")
+	contextLogger.Flush()
+
+	if len(out.levels) != 2 || out.levels[0] != InfoLevel || out.levels[1] != WarnLevel {
+		t.Errorf("Expected one write per entry with its level, got %v", out.levels)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 2 {
+		t.Errorf("Expected 2 newline-terminated documents, got %q", out.String())
+	}
+}
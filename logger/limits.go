@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// truncateMessage shortens the message of the entry to at most maxBytes bytes without
+// splitting a UTF-8 character, and marks the entry as truncated. 0 means no limit
+func truncateMessage(entry *LogEntry, maxBytes int) {
+	if maxBytes <= 0 || len(entry.Message) <= maxBytes {
+		return
+	}
+
+	entry.Message = truncateUTF8(entry.Message, maxBytes)
+	entry.Truncated = true
+}
+
+// truncateUTF8 returns the longest prefix of s that is at most maxBytes bytes long and does
+// not split a UTF-8 character
+func truncateUTF8(s string, maxBytes int) string {
+	if maxBytes <= 0 {
+		return ""
+	}
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// enforceLineLimit drops the oldest line after the first headLines lines when the logger
+// holds more than headLines+tailLines lines, so that the first and the last lines are kept
+// The severity of the dropped line is kept as the severity floor of the next flush.
+// This method assumes the mutex is already held by the caller
+func (l *ContextLogger) enforceLineLimit() {
+	limit := l.headLines + l.tailLines
+	if limit <= 0 || len(l.entries) <= limit {
+		return
+	}
+
+	dropped := l.entries[l.headLines]
+	copy(l.entries[l.headLines:], l.entries[l.headLines+1:])
+	l.entries[len(l.entries)-1] = nil
+	l.entries = l.entries[:len(l.entries)-1]
+
+	l.droppedLines++
+	l.severity = GetHigherLevel(l.severity, ParseLogLevel(dropped.Level))
+	putLogEntry(dropped)
+}
+
+// formatRecordsWithLimit formats the LogOutput like formatRecords and, if a record is larger
+// than maxBytes, first shortens the messages of lines that do not fit even on their own, then
+// drops lines from the middle of the document until every record fits.
+// The document passed in is not modified. If the document does not fit even without lines,
+// the error handler is notified and the records are returned anyway. 0 means no limit
+func formatRecordsWithLimit(logOutput *formatter.LogOutput, f formatter.Formatter, maxBytes int) ([]formatter.Record, error) {
+	records, err := formatRecords(logOutput, f)
+	if err != nil || maxBytes <= 0 || largestRecord(records) <= maxBytes {
+		return records, err
+	}
+
+	// With record formatters each line is usually its own record, and dropping other lines
+	// would not make an oversized line fit
+	shortened, err := truncateOversizedLines(logOutput, f, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if shortened != nil {
+		logOutput = shortened
+		records, err = formatRecords(logOutput, f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keep := len(logOutput.Runtime.Lines)
+	for {
+		size := largestRecord(records)
+		if size <= maxBytes {
+			return records, nil
+		}
+		if keep == 0 {
+			handleError("output_size", fmt.Errorf("log output of %d bytes exceeds the limit of %d bytes", size, maxBytes))
+			return records, nil
+		}
+
+		// Estimate the number of lines that fit, and always drop at least one
+		next := int(int64(keep) * int64(maxBytes) / int64(size))
+		if next >= keep {
+			next = keep - 1
+		}
+		keep = next
+
+		records, err = formatRecords(keepLines(logOutput, keep), f)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// truncateOversizedLines returns a copy of the LogOutput in which the messages of lines that
+// are larger than maxBytes when formatted on their own are shortened and marked as truncated,
+// or nil if no line was shortened
+func truncateOversizedLines(logOutput *formatter.LogOutput, f formatter.Formatter, maxBytes int) (*formatter.LogOutput, error) {
+	var lines []*formatter.LogEntry
+	for i, line := range logOutput.Runtime.Lines {
+		shortened, err := truncateLine(logOutput, line, f, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		if shortened == nil {
+			continue
+		}
+		if lines == nil {
+			lines = make([]*formatter.LogEntry, len(logOutput.Runtime.Lines))
+			copy(lines, logOutput.Runtime.Lines)
+		}
+		lines[i] = shortened
+	}
+	if lines == nil {
+		return nil, nil
+	}
+
+	trimmed := *logOutput
+	trimmed.Runtime.Lines = lines
+	return &trimmed, nil
+}
+
+// truncateLine returns a copy of the line with its message shortened so that the document
+// holding only this line fits in maxBytes, or nil if the line already fits or does not fit
+// even with an empty message
+func truncateLine(logOutput *formatter.LogOutput, line *formatter.LogEntry, f formatter.Formatter, maxBytes int) (*formatter.LogEntry, error) {
+	entry := *line
+	single := *logOutput
+	single.Runtime.Lines = []*formatter.LogEntry{&entry}
+
+	for {
+		records, err := formatRecords(&single, f)
+		if err != nil {
+			return nil, err
+		}
+		size := largestRecord(records)
+		if size <= maxBytes {
+			if !entry.Truncated || entry.Message == line.Message {
+				return nil, nil
+			}
+			return &entry, nil
+		}
+		if entry.Message == "" {
+			return nil, nil
+		}
+
+		// Removing n bytes of the message removes at least n bytes of the record
+		entry.Message = truncateUTF8(entry.Message, len(entry.Message)-(size-maxBytes))
+		entry.Truncated = true
+	}
+}
+
+// keepLines returns a copy of the LogOutput that keeps the first and last lines, keep in
+// total, and records the dropped lines
+func keepLines(logOutput *formatter.LogOutput, keep int) *formatter.LogOutput {
+	lines := logOutput.Runtime.Lines
+	head := keep / 2
+	tail := keep - head
+
+	kept := make([]*formatter.LogEntry, 0, keep)
+	kept = append(kept, lines[:head]...)
+	kept = append(kept, lines[len(lines)-tail:]...)
+
+	trimmed := *logOutput
+	trimmed.Runtime.Lines = kept
+	trimmed.Runtime.Truncated = true
+	trimmed.Runtime.DroppedLines += len(lines) - keep
+	return &trimmed
+}
+
+// largestRecord returns the size of the largest record
+func largestRecord(records []formatter.Record) int {
+	largest := 0
+	for _, record := range records {
+		if len(record.Data) > largest {
+			largest = len(record.Data)
+		}
+	}
+	return largest
+}
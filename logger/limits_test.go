@@ -0,0 +1,301 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// decodeOutput decodes a single JSON document written by a logger
+func decodeOutput(t *testing.T, buf *bytes.Buffer) formatter.LogOutput {
+	t.Helper()
+	var output formatter.LogOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to decode output: %v\n%s", err, buf.String())
+	}
+	return output
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		maxBytes  int
+		expected  string
+		truncated bool
+	}{
+		{"no limit", "hello world", 0, "hello world", false},
+		{"short", "hello", 10, "hello", false},
+		{"exact", "hello", 5, "hello", false},
+		{"long", "hello world", 5, "hello", true},
+		{"multibyte boundary", "こんにちは", 7, "こん", true},
+		{"multibyte exact", "こんにちは", 6, "こん", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &LogEntry{Message: tt.message}
+			truncateMessage(entry, tt.maxBytes)
+			if entry.Message != tt.expected || entry.Truncated != tt.truncated {
+				t.Errorf("Expected %q (truncated=%v), got %q (truncated=%v)", tt.expected, tt.truncated, entry.Message, entry.Truncated)
+			}
+		})
+	}
+}
+
+func TestMaxMessageLength(t *testing.T) {
+	Init(WithMaxMessageLength(8))
+	defer Init()
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(WithContextOutput(&buf), WithContextFormatter(formatter.NewJSONFormatter()))
+	contextLogger.Infof("short")
+	contextLogger.Infof("a much longer message")
+	contextLogger.Flush()
+
+	output := decodeOutput(t, &buf)
+	lines := output.Runtime.Lines
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].Message != "short" || lines[0].Truncated {
+		t.Errorf("Expected the short message unchanged, got %+v", lines[0])
+	}
+	if lines[1].Message != "a much l" || !lines[1].Truncated {
+		t.Errorf("Expected a truncated message, got %+v", lines[1])
+	}
+
+	// DirectLogger applies the same limit
+	buf.Reset()
+	directLogger := NewDirectLogger()
+	directLogger.SetOutput(&buf)
+	directLogger.SetFormatter(formatter.NewJSONFormatter())
+	directLogger.Infof("another long message")
+	if !strings.Contains(buf.String(), `"message":"another ","truncated":true`) {
+		t.Errorf("Expected a truncated direct log line, got %s", buf.String())
+	}
+}
+
+func TestLineLimit_KeepsHeadAndTail(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(
+		WithContextOutput(&buf),
+		WithContextFormatter(formatter.NewJSONFormatter()),
+		WithContextLineLimit(2, 3),
+	)
+
+	for i := 1; i <= 10; i++ {
+		if i == 4 {
+			contextLogger.Errorf("line %d", i)
+			continue
+		}
+		contextLogger.Infof("line %d", i)
+	}
+	contextLogger.Flush()
+
+	output := decodeOutput(t, &buf)
+	var messages []string
+	for _, line := range output.Runtime.Lines {
+		messages = append(messages, line.Message)
+	}
+	if got := strings.Join(messages, ","); got != "line 1,line 2,line 8,line 9,line 10" {
+		t.Errorf("Expected the first 2 and last 3 lines, got %s", got)
+	}
+	if !output.Runtime.Truncated || output.Runtime.DroppedLines != 5 {
+		t.Errorf("Expected truncated with 5 dropped lines, got %v/%d", output.Runtime.Truncated, output.Runtime.DroppedLines)
+	}
+	if output.Runtime.Severity != "ERROR" {
+		t.Errorf("Expected the severity of the dropped ERROR line, got %s", output.Runtime.Severity)
+	}
+
+	// The counters are reset after a flush
+	buf.Reset()
+	contextLogger.Infof("next")
+	contextLogger.Flush()
+	output = decodeOutput(t, &buf)
+	if output.Runtime.Truncated || output.Runtime.DroppedLines != 0 || output.Runtime.Severity != "INFO" {
+		t.Errorf("Expected a fresh flush, got %+v", output.Runtime)
+	}
+	if strings.Contains(buf.String(), "droppedLines") {
+		t.Errorf("Expected no droppedLines marker, got %s", buf.String())
+	}
+}
+
+func TestLineLimit_HeadOnlyAndTailOnly(t *testing.T) {
+	tests := []struct {
+		name       string
+		head, tail int
+		expected   string
+	}{
+		{"head only", 2, 0, "line 1,line 2"},
+		{"tail only", 0, 2, "line 4,line 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			contextLogger := NewContextLoggerWithOptions(
+				WithContextOutput(&buf),
+				WithContextFormatter(formatter.NewJSONFormatter()),
+				WithContextLineLimit(tt.head, tt.tail),
+			)
+			for i := 1; i <= 5; i++ {
+				contextLogger.Infof("line %d", i)
+			}
+			contextLogger.Flush()
+
+			output := decodeOutput(t, &buf)
+			var messages []string
+			for _, line := range output.Runtime.Lines {
+				messages = append(messages, line.Message)
+			}
+			if got := strings.Join(messages, ","); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+			if output.Runtime.DroppedLines != 3 {
+				t.Errorf("Expected 3 dropped lines, got %d", output.Runtime.DroppedLines)
+			}
+		})
+	}
+}
+
+func TestLineLimit_FromGlobalConfig(t *testing.T) {
+	Init(WithLineLimit(1, 1))
+	defer Init()
+
+	contextLogger := NewContextLogger()
+	if contextLogger.headLines != 1 || contextLogger.tailLines != 1 {
+		t.Errorf("Expected the global line limit, got %d/%d", contextLogger.headLines, contextLogger.tailLines)
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	Init(WithMaxOutputBytes(2048))
+	defer Init()
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(WithContextOutput(&buf), WithContextFormatter(formatter.NewJSONFormatter()))
+	for i := 1; i <= 200; i++ {
+		contextLogger.Infof("line %03d with some padding to make the document larger", i)
+	}
+	contextLogger.Flush()
+
+	data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	if len(data) > 2048 {
+		t.Errorf("Expected at most 2048 bytes, got %d", len(data))
+	}
+
+	output := decodeOutput(t, &buf)
+	lines := output.Runtime.Lines
+	if len(lines) == 0 || !output.Runtime.Truncated || output.Runtime.DroppedLines != 200-len(lines) {
+		t.Fatalf("Expected dropped lines to be recorded, got %d lines and %+v", len(lines), output.Runtime)
+	}
+	if !strings.HasPrefix(lines[0].Message, "line 001") || !strings.HasPrefix(lines[len(lines)-1].Message, "line 200") {
+		t.Errorf("Expected the first and last lines to be kept, got %q and %q", lines[0].Message, lines[len(lines)-1].Message)
+	}
+}
+
+func TestMaxOutputBytes_TruncatesOversizedLine(t *testing.T) {
+	Init(WithMaxOutputBytes(512))
+	defer Init()
+
+	long := strings.Repeat("x", 1000)
+
+	t.Run("record formatter", func(t *testing.T) {
+		var buf bytes.Buffer
+		contextLogger := NewContextLoggerWithOptions(WithContextOutput(&buf), WithContextFormatter(formatter.NewExplodedJSONFormatter()))
+		contextLogger.Infof("before")
+		contextLogger.Infof("%s", long)
+		contextLogger.Infof("after")
+		contextLogger.Flush()
+
+		documents := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(documents) != 3 {
+			t.Fatalf("Expected every line to be kept, got %d documents:\n%s", len(documents), buf.String())
+		}
+		for i, document := range documents {
+			if len(document) > 512 {
+				t.Errorf("Expected document %d to be at most 512 bytes, got %d", i, len(document))
+			}
+		}
+		if !strings.Contains(documents[0], `"message":"before"`) || !strings.Contains(documents[2], `"message":"after"`) {
+			t.Errorf("Expected the lines that fit to be unchanged, got %s", buf.String())
+		}
+		if !strings.Contains(documents[1], `"message":"xxx`) || !strings.Contains(documents[1], `"truncated":true`) {
+			t.Errorf("Expected the oversized line to be truncated, got %s", documents[1])
+		}
+		if strings.Contains(buf.String(), "droppedLines") {
+			t.Errorf("Expected no dropped lines, got %s", buf.String())
+		}
+	})
+
+	t.Run("direct logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		directLogger := NewDirectLogger()
+		directLogger.SetOutput(&buf)
+		directLogger.SetFormatter(formatter.NewJSONFormatter())
+		directLogger.Infof("%s", long)
+
+		data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+		if len(data) > 512 {
+			t.Errorf("Expected at most 512 bytes, got %d", len(data))
+		}
+		output := decodeOutput(t, &buf)
+		if len(output.Runtime.Lines) != 1 || output.Runtime.DroppedLines != 0 {
+			t.Fatalf("Expected the line to be kept, got %+v", output.Runtime)
+		}
+		line := output.Runtime.Lines[0]
+		if !line.Truncated || !strings.HasPrefix(line.Message, "xxx") || len(line.Message) >= len(long) {
+			t.Errorf("Expected a truncated message, got %+v", line)
+		}
+	})
+}
+
+func TestFormatRecordsWithLimit(t *testing.T) {
+	lines := make([]*formatter.LogEntry, 50)
+	for i := range lines {
+		lines[i] = &formatter.LogEntry{Level: "INFO", Message: fmt.Sprintf("line %d", i)}
+	}
+	logOutput := &formatter.LogOutput{
+		Type:    "request",
+		Context: map[string]interface{}{"request_id": "req-1"},
+		Runtime: formatter.RuntimeInfo{Severity: "INFO", Lines: lines},
+	}
+
+	t.Run("does not modify the document", func(t *testing.T) {
+		records, err := formatRecordsWithLimit(logOutput, formatter.NewJSONFormatter(), 1024)
+		if err != nil {
+			t.Fatalf("formatRecordsWithLimit failed: %v", err)
+		}
+		if len(records[0].Data) > 1024 {
+			t.Errorf("Expected at most 1024 bytes, got %d", len(records[0].Data))
+		}
+		if len(logOutput.Runtime.Lines) != 50 || logOutput.Runtime.Truncated {
+			t.Errorf("Expected the original document to be unchanged")
+		}
+	})
+
+	t.Run("reports documents that cannot fit", func(t *testing.T) {
+		var reported []string
+		previous := GetGlobalErrorHandler()
+		SetGlobalErrorHandler(ErrorHandlerFunc(func(operation string, err error) {
+			reported = append(reported, operation)
+		}))
+		defer SetGlobalErrorHandler(previous)
+
+		records, err := formatRecordsWithLimit(logOutput, formatter.NewJSONFormatter(), 10)
+		if err != nil {
+			t.Fatalf("formatRecordsWithLimit failed: %v", err)
+		}
+		if len(records) != 1 || !strings.Contains(string(records[0].Data), `"droppedLines":50`) {
+			t.Errorf("Expected the document without lines, got %s", records[0].Data)
+		}
+		if len(reported) != 1 || reported[0] != "output_size" {
+			t.Errorf("Expected an output_size error, got %v", reported)
+		}
+	})
+}
@@ -18,6 +18,88 @@ type Logger interface {
 	Criticalf(format string, args ...interface{})
 }
 
+// StructuredLogger extends Logger with methods that take structured key/value fields
+// DirectLogger and ContextLogger implement it. It is separate from Logger so that existing
+// implementations of Logger keep satisfying it.
+type StructuredLogger interface {
+	Logger
+
+	// Debugw logs a debug message with structured key/value fields
+	Debugw(msg string, keysAndValues ...interface{})
+
+	// Infow logs an info message with structured key/value fields
+	Infow(msg string, keysAndValues ...interface{})
+
+	// Warnw logs a warning message with structured key/value fields
+	Warnw(msg string, keysAndValues ...interface{})
+
+	// Errorw logs an error message with structured key/value fields
+	Errorw(msg string, keysAndValues ...interface{})
+
+	// Criticalw logs a critical message with structured key/value fields
+	Criticalw(msg string, keysAndValues ...interface{})
+}
+
 // D is the global direct logger instance
-// Usage: logger.D.Infof("message", args...)
+// Usage: logger.D.Infof("message", args...) or logger.Structured(logger.D).Infow("message", "key", value)
 var D Logger = NewDirectLogger()
+
+// Structured returns the logger as a StructuredLogger
+// Loggers that implement only Logger are wrapped, and their *w methods append the fields to
+// the message as key=value pairs in key order.
+//
+// Usage:
+//
+//	logger.Structured(logger.D).Infow("order placed", "order_id", id)
+func Structured(l Logger) StructuredLogger {
+	if structured, ok := l.(StructuredLogger); ok {
+		return structured
+	}
+	return formatLogger{l}
+}
+
+// formatLogger adapts a Logger to StructuredLogger, see Structured
+type formatLogger struct {
+	Logger
+}
+
+// Debugw logs a debug message with the fields appended
+func (l formatLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.Debugf("%s", appendFields(msg, keysAndValues))
+}
+
+// Infow logs an info message with the fields appended
+func (l formatLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.Infof("%s", appendFields(msg, keysAndValues))
+}
+
+// Warnw logs a warning message with the fields appended
+func (l formatLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.Warnf("%s", appendFields(msg, keysAndValues))
+}
+
+// Errorw logs an error message with the fields appended
+func (l formatLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.Errorf("%s", appendFields(msg, keysAndValues))
+}
+
+// Criticalw logs a critical message with the fields appended
+func (l formatLogger) Criticalw(msg string, keysAndValues ...interface{}) {
+	l.Criticalf("%s", appendFields(msg, keysAndValues))
+}
+
+// logAtLevel calls the *w method of the logger that matches the level
+func logAtLevel(l StructuredLogger, level LogLevel, msg string, keysAndValues ...interface{}) {
+	switch level {
+	case DebugLevel:
+		l.Debugw(msg, keysAndValues...)
+	case InfoLevel:
+		l.Infow(msg, keysAndValues...)
+	case WarnLevel:
+		l.Warnw(msg, keysAndValues...)
+	case ErrorLevel:
+		l.Errorw(msg, keysAndValues...)
+	case CriticalLevel:
+		l.Criticalw(msg, keysAndValues...)
+	}
+}
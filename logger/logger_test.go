@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+)
+
+// formatOnlyLogger implements only the format methods, like loggers and mocks written
+// against the original Logger interface
+type formatOnlyLogger struct {
+	messages []string
+}
+
+func (l *formatOnlyLogger) Debugf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *formatOnlyLogger) Infof(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *formatOnlyLogger) Warnf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *formatOnlyLogger) Errorf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *formatOnlyLogger) Criticalf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestLoggerInterfaces(t *testing.T) {
+	// Implementations of Logger alone still satisfy it, and can replace D
+	existing := &formatOnlyLogger{}
+	previous := D
+	D = existing
+	defer func() { D = previous }()
+	D.Infof("still a Logger")
+	if len(existing.messages) != 1 {
+		t.Errorf("Expected the format-only logger to be used, got %v", existing.messages)
+	}
+
+	// The built-in loggers provide the structured methods as well
+	for _, l := range []Logger{NewDirectLogger(), NewContextLogger()} {
+		if _, ok := Structured(l).(formatLogger); ok {
+			t.Errorf("Expected %T to be used as a StructuredLogger without wrapping", l)
+		}
+	}
+}
+
+func TestStructured_WrapsFormatOnlyLogger(t *testing.T) {
+	existing := &formatOnlyLogger{}
+	Structured(existing).Infow("order placed", "order_id", "ord-1", "amount", 42)
+
+	if len(existing.messages) != 1 || existing.messages[0] != "order placed amount=42 order_id=ord-1" {
+		t.Errorf("Expected the fields appended in key order, got %v", existing.messages)
+	}
+}
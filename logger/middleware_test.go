@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 	"time"
@@ -422,3 +423,30 @@ func TestContextLoggerWithMiddleware(t *testing.T) {
 	// Clean up
 	ClearMiddleware()
 }
+
+func TestMiddleware_StructuredFields(t *testing.T) {
+	ClearMiddleware()
+	defer ClearMiddleware()
+
+	var seen map[string]interface{}
+	AddMiddleware(func(entry *LogEntry, next func(*LogEntry)) {
+		seen = entry.Fields
+		if entry.Fields != nil {
+			entry.Fields["enriched"] = true
+		}
+		next(entry)
+	})
+
+	var buf bytes.Buffer
+	logger := NewContextLogger()
+	logger.SetOutput(&buf)
+	logger.Infow("query executed", "db_rows", 3)
+	logger.Flush()
+
+	if seen["db_rows"] != 3 {
+		t.Errorf("Expected middleware to see db_rows=3, got %v", seen)
+	}
+	if !strings.Contains(buf.String(), `"enriched"`) {
+		t.Errorf("Expected middleware changes to reach the output, got %s", buf.String())
+	}
+}
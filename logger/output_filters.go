@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"github.com/zentooo/logspan/formatter"
+)
+
+// DefaultLineCountsKey is the context key written by CountLinesByLevel when no key is given
+const DefaultLineCountsKey = "line_counts"
+
+// FilterLines creates an output middleware that keeps only the lines for which keep returns true
+// keep sees the whole document, so the decision can depend on the context and on the aggregate
+// severity, which still accounts for the removed lines. A document left without lines and
+// without context, such as a filtered DirectLogger line, is dropped.
+func FilterLines(keep func(output *formatter.LogOutput, line *formatter.LogEntry) bool) OutputMiddleware {
+	return func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		lines := make([]*formatter.LogEntry, 0, len(output.Runtime.Lines))
+		for _, line := range output.Runtime.Lines {
+			if keep(output, line) {
+				lines = append(lines, line)
+			}
+		}
+
+		if len(lines) < len(output.Runtime.Lines) {
+			if len(lines) == 0 && len(output.Context) == 0 {
+				return
+			}
+			output.Runtime.Lines = lines
+		}
+		next(output)
+	}
+}
+
+// DropLinesBelow creates an output middleware that removes the lines below level, unless the
+// aggregate severity of the document is at least severity
+//
+// Usage:
+//
+//	// Keep DEBUG lines only for requests that failed
+//	logger.AddOutputMiddleware(logger.DropLinesBelow(logger.InfoLevel, logger.ErrorLevel))
+func DropLinesBelow(level, severity LogLevel) OutputMiddleware {
+	return FilterLines(func(output *formatter.LogOutput, line *formatter.LogEntry) bool {
+		return IsLevelEnabled(ParseLogLevel(line.Level), level) ||
+			IsLevelEnabled(ParseLogLevel(output.Runtime.Severity), severity)
+	})
+}
+
+// DebugLinesOnError creates an output middleware that keeps DEBUG lines only in documents
+// whose aggregate severity is ERROR or above. It is DropLinesBelow(InfoLevel, ErrorLevel)
+// Loggers must be configured with DebugLevel for DEBUG lines to be collected at all.
+func DebugLinesOnError() OutputMiddleware {
+	return DropLinesBelow(InfoLevel, ErrorLevel)
+}
+
+// EnrichContext creates an output middleware that adds the values returned by fn to the
+// context of the document. Existing keys are overwritten; the logger's context is not modified
+//
+// Usage:
+//
+//	logger.AddOutputMiddleware(logger.EnrichContext(func(output *formatter.LogOutput) map[string]interface{} {
+//	    return map[string]interface{}{"slow": output.Runtime.Elapsed > 1000}
+//	}))
+func EnrichContext(fn func(output *formatter.LogOutput) map[string]interface{}) OutputMiddleware {
+	return func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		if values := fn(output); len(values) > 0 {
+			context := make(map[string]interface{}, len(output.Context)+len(values))
+			for k, v := range output.Context {
+				context[k] = v
+			}
+			for k, v := range values {
+				context[k] = v
+			}
+			output.Context = context
+		}
+		next(output)
+	}
+}
+
+// CountLinesByLevel creates an output middleware that adds the number of lines per level
+// to the context under key (DefaultLineCountsKey if empty), e.g. {"INFO": 3, "DEBUG": 12}
+// Only the lines present when it runs are counted, so add it before line filters to count
+// every line
+func CountLinesByLevel(key string) OutputMiddleware {
+	if key == "" {
+		key = DefaultLineCountsKey
+	}
+	return EnrichContext(func(output *formatter.LogOutput) map[string]interface{} {
+		counts := make(map[string]int)
+		for _, line := range output.Runtime.Lines {
+			counts[line.Level]++
+		}
+		return map[string]interface{}{key: counts}
+	})
+}
+
+// DropOutput creates an output middleware that vetoes the documents for which drop returns true
+// The lines of a vetoed ContextLogger flush are discarded like written ones.
+func DropOutput(drop func(output *formatter.LogOutput) bool) OutputMiddleware {
+	return func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		if drop(output) {
+			return
+		}
+		next(output)
+	}
+}
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// flushWithOutputMiddleware logs the lines through a ContextLogger that uses only the given
+// output middleware and returns the written document, or nil if nothing was written
+func flushWithOutputMiddleware(t *testing.T, middleware OutputMiddleware, log func(l *ContextLogger)) *formatter.LogOutput {
+	t.Helper()
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(
+		WithContextOutput(&buf),
+		WithContextMinLevel(DebugLevel),
+		WithContextOutputMiddleware(middleware),
+		WithContextGlobalMiddleware(false),
+	)
+	log(contextLogger)
+	contextLogger.Flush()
+
+	if buf.Len() == 0 {
+		return nil
+	}
+	output := decodeOutput(t, &buf)
+	return &output
+}
+
+func TestDebugLinesOnError(t *testing.T) {
+	success := flushWithOutputMiddleware(t, DebugLinesOnError(), func(l *ContextLogger) {
+		l.AddContextValue("path", "/ok")
+		l.Debugf("query plan")
+		l.Infof("done")
+	})
+	if len(success.Runtime.Lines) != 1 || success.Runtime.Lines[0].Message != "done" {
+		t.Errorf("Expected DEBUG lines to be dropped, got %+v", success.Runtime.Lines)
+	}
+	if success.Runtime.Truncated || success.Runtime.DroppedLines != 0 {
+		t.Error("Expected filtered lines not to be reported as truncated")
+	}
+
+	failure := flushWithOutputMiddleware(t, DebugLinesOnError(), func(l *ContextLogger) {
+		l.Debugf("query plan")
+		l.Errorf("failed")
+	})
+	if len(failure.Runtime.Lines) != 2 {
+		t.Errorf("Expected DEBUG lines to be kept on error, got %+v", failure.Runtime.Lines)
+	}
+}
+
+func TestDropLinesBelow_SeverityFromDroppedLines(t *testing.T) {
+	// The aggregate severity still accounts for the removed lines
+	output := flushWithOutputMiddleware(t, DropLinesBelow(ErrorLevel, CriticalLevel), func(l *ContextLogger) {
+		l.AddContextValue("path", "/api")
+		l.Warnf("slow")
+	})
+	if output == nil {
+		t.Fatal("Expected document with context to be written")
+	}
+	if len(output.Runtime.Lines) != 0 || output.Runtime.Severity != "WARN" {
+		t.Errorf("Expected no lines and WARN severity, got %+v", output.Runtime)
+	}
+}
+
+func TestFilterLines_DropsEmptyDocument(t *testing.T) {
+	ClearOutputMiddleware()
+	defer ClearOutputMiddleware()
+	AddOutputMiddleware(DebugLinesOnError())
+
+	var buf bytes.Buffer
+	directLogger := NewDirectLogger()
+	directLogger.SetOutput(&buf)
+	directLogger.SetLevel(DebugLevel)
+	directLogger.Debugf("noise")
+	directLogger.Infof("kept")
+
+	output := buf.String()
+	if strings.Contains(output, "noise") || strings.Count(output, "\n") != 1 {
+		t.Errorf("Expected only the INFO line to be written, got %s", output)
+	}
+}
+
+func TestCountLinesByLevel(t *testing.T) {
+	chain := func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		CountLinesByLevel("")(output, func(o *formatter.LogOutput) {
+			DebugLinesOnError()(o, next)
+		})
+	}
+
+	output := flushWithOutputMiddleware(t, chain, func(l *ContextLogger) {
+		l.Debugf("a")
+		l.Debugf("b")
+		l.Infof("c")
+	})
+
+	counts, ok := output.Context[DefaultLineCountsKey].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected line counts in context, got %v", output.Context)
+	}
+	if counts["DEBUG"] != float64(2) || counts["INFO"] != float64(1) {
+		t.Errorf("Expected counts before filtering, got %v", counts)
+	}
+	if len(output.Runtime.Lines) != 1 {
+		t.Errorf("Expected DEBUG lines to be filtered after counting, got %d lines", len(output.Runtime.Lines))
+	}
+}
+
+func TestEnrichContext(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(
+		WithContextOutput(&buf),
+		WithContextOutputMiddleware(EnrichContext(func(output *formatter.LogOutput) map[string]interface{} {
+			return map[string]interface{}{"line_total": len(output.Runtime.Lines), "user": "overwritten"}
+		})),
+	)
+	contextLogger.AddContextValue("user", "john")
+	contextLogger.Infof("a")
+	contextLogger.Flush()
+
+	output := decodeOutput(t, &buf)
+	if output.Context["line_total"] != float64(1) || output.Context["user"] != "overwritten" {
+		t.Errorf("Expected computed context, got %v", output.Context)
+	}
+
+	contextLogger.mutex.Lock()
+	user := contextLogger.fields["user"]
+	contextLogger.mutex.Unlock()
+	if user != "john" {
+		t.Errorf("Expected logger context to be unchanged, got %v", user)
+	}
+}
+
+func TestDropOutput(t *testing.T) {
+	veto := DropOutput(func(output *formatter.LogOutput) bool {
+		return output.Context["path"] == "/healthz"
+	})
+
+	if output := flushWithOutputMiddleware(t, veto, func(l *ContextLogger) {
+		l.AddContextValue("path", "/healthz")
+		l.Infof("ok")
+	}); output != nil {
+		t.Errorf("Expected document to be vetoed, got %+v", output)
+	}
+
+	if output := flushWithOutputMiddleware(t, veto, func(l *ContextLogger) {
+		l.AddContextValue("path", "/api")
+		l.Infof("ok")
+	}); output == nil {
+		t.Error("Expected document to be written")
+	}
+}
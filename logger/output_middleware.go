@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"sync"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// OutputMiddleware defines the interface for document processing middleware
+// OutputMiddleware runs when a document is written (a ContextLogger flush or a DirectLogger
+// line), after all context values and lines are known, and before the document is formatted
+// or passed to a sink. It receives the document and a next function, and calls next to continue
+// the chain; a middleware that does not call next drops the document.
+//
+// The Context map and the Fields of the lines may be shared with the logger and the caller,
+// so a middleware that changes them must replace the maps instead of modifying them in place.
+type OutputMiddleware func(output *formatter.LogOutput, next func(*formatter.LogOutput))
+
+// OutputMiddlewareChain manages a chain of output middleware functions
+type OutputMiddlewareChain struct {
+	middlewares []OutputMiddleware
+}
+
+// NewOutputMiddlewareChain creates a new output middleware chain
+func NewOutputMiddlewareChain() *OutputMiddlewareChain {
+	return &OutputMiddlewareChain{
+		middlewares: make([]OutputMiddleware, 0),
+	}
+}
+
+// Add appends an output middleware to the chain
+func (mc *OutputMiddlewareChain) Add(middleware OutputMiddleware) {
+	mc.middlewares = append(mc.middlewares, middleware)
+}
+
+// Process executes the output middleware chain on a document
+// The final function in the chain is called when all middleware have been processed
+func (mc *OutputMiddlewareChain) Process(output *formatter.LogOutput, final func(*formatter.LogOutput)) {
+	next := final
+	for i := len(mc.middlewares) - 1; i >= 0; i-- {
+		middleware := mc.middlewares[i]
+		currentNext := next
+		next = func(o *formatter.LogOutput) {
+			middleware(o, currentNext)
+		}
+	}
+	next(output)
+}
+
+// Clear removes all output middleware from the chain
+func (mc *OutputMiddlewareChain) Clear() {
+	mc.middlewares = mc.middlewares[:0]
+}
+
+// Count returns the number of output middleware in the chain
+func (mc *OutputMiddlewareChain) Count() int {
+	return len(mc.middlewares)
+}
+
+// Global output middleware management
+var (
+	globalOutputMiddlewareChain = NewOutputMiddlewareChain()
+	outputMiddlewareMutex       sync.RWMutex
+)
+
+// AddOutputMiddleware adds an output middleware to the global output middleware chain
+// This middleware will be applied to every document written by the loggers
+func AddOutputMiddleware(middleware OutputMiddleware) {
+	outputMiddlewareMutex.Lock()
+	defer outputMiddlewareMutex.Unlock()
+	globalOutputMiddlewareChain.Add(middleware)
+}
+
+// ClearOutputMiddleware removes all output middleware from the global chain
+func ClearOutputMiddleware() {
+	outputMiddlewareMutex.Lock()
+	defer outputMiddlewareMutex.Unlock()
+	globalOutputMiddlewareChain.Clear()
+}
+
+// GetOutputMiddlewareCount returns the number of output middleware in the global chain
+func GetOutputMiddlewareCount() int {
+	outputMiddlewareMutex.RLock()
+	defer outputMiddlewareMutex.RUnlock()
+	return globalOutputMiddlewareChain.Count()
+}
+
+// processWithGlobalOutputMiddleware processes a document through the global output middleware chain
+func processWithGlobalOutputMiddleware(output *formatter.LogOutput, final func(*formatter.LogOutput)) {
+	outputMiddlewareMutex.RLock()
+	defer outputMiddlewareMutex.RUnlock()
+	globalOutputMiddlewareChain.Process(output, final)
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+func TestOutputMiddlewareChain_Process(t *testing.T) {
+	chain := NewOutputMiddlewareChain()
+
+	var order []string
+	chain.Add(func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		order = append(order, "first")
+		output.Type = "first"
+		next(output)
+	})
+	chain.Add(func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		order = append(order, "second")
+		output.Type += "+second"
+		next(output)
+	})
+
+	var result *formatter.LogOutput
+	chain.Process(&formatter.LogOutput{}, func(output *formatter.LogOutput) {
+		result = output
+	})
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("Expected middleware to run in order, got %v", order)
+	}
+	if result == nil || result.Type != "first+second" {
+		t.Errorf("Expected processed output, got %+v", result)
+	}
+	if chain.Count() != 2 {
+		t.Errorf("Expected 2 middleware, got %d", chain.Count())
+	}
+
+	chain.Clear()
+	if chain.Count() != 0 {
+		t.Errorf("Expected 0 middleware after Clear, got %d", chain.Count())
+	}
+}
+
+func TestAddOutputMiddleware_ContextLogger(t *testing.T) {
+	ClearOutputMiddleware()
+	defer ClearOutputMiddleware()
+
+	AddOutputMiddleware(func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		output.Context = map[string]interface{}{"added": "at_flush"}
+		next(output)
+	})
+	if GetOutputMiddlewareCount() != 1 {
+		t.Errorf("Expected 1 output middleware, got %d", GetOutputMiddlewareCount())
+	}
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(WithContextOutput(&buf))
+	contextLogger.AddContextValue("original", "value")
+	contextLogger.Infof("message")
+	contextLogger.Flush()
+
+	output := decodeOutput(t, &buf)
+	if output.Context["added"] != "at_flush" {
+		t.Errorf("Expected context replaced by output middleware, got %v", output.Context)
+	}
+	if _, ok := output.Context["original"]; ok {
+		t.Errorf("Expected original context to be replaced, got %v", output.Context)
+	}
+
+	// The logger's own context is not modified
+	contextLogger.mutex.Lock()
+	original := contextLogger.fields["original"]
+	contextLogger.mutex.Unlock()
+	if original != "value" {
+		t.Errorf("Expected logger context to be unchanged, got %v", original)
+	}
+}
+
+func TestAddOutputMiddleware_Drop(t *testing.T) {
+	ClearOutputMiddleware()
+	defer ClearOutputMiddleware()
+
+	AddOutputMiddleware(func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		if output.Runtime.Severity == "DEBUG" {
+			return
+		}
+		next(output)
+	})
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(WithContextOutput(&buf), WithContextMinLevel(DebugLevel))
+	contextLogger.Debugf("dropped")
+	contextLogger.Flush()
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected document to be dropped, got %s", buf.String())
+	}
+
+	// The dropped lines are not carried over to the next flush
+	contextLogger.Infof("kept")
+	contextLogger.Flush()
+
+	output := buf.String()
+	if strings.Contains(output, "dropped") || !strings.Contains(output, "kept") {
+		t.Errorf("Expected only the second document, got %s", output)
+	}
+}
+
+func TestAddOutputMiddleware_DirectLogger(t *testing.T) {
+	ClearOutputMiddleware()
+	defer ClearOutputMiddleware()
+
+	AddOutputMiddleware(func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		output.Type = "direct"
+		next(output)
+	})
+
+	var buf bytes.Buffer
+	directLogger := NewDirectLogger()
+	directLogger.SetOutput(&buf)
+	directLogger.Infof("message")
+
+	if !strings.Contains(buf.String(), `"type":"direct"`) {
+		t.Errorf("Expected output middleware to apply to DirectLogger, got %s", buf.String())
+	}
+}
+
+func TestAddOutputMiddleware_Sink(t *testing.T) {
+	ClearOutputMiddleware()
+	defer ClearOutputMiddleware()
+
+	AddOutputMiddleware(func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		output.Type = "sink"
+		next(output)
+	})
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(WithContextSink(NewWriterSink(&buf)))
+	ctx := WithLogger(context.Background(), contextLogger)
+	Infof(ctx, "message")
+	FlushContext(ctx)
+
+	if !strings.Contains(buf.String(), `"type":"sink"`) {
+		t.Errorf("Expected output middleware to run before the sink, got %s", buf.String())
+	}
+}
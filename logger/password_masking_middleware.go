@@ -1,12 +1,24 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
+
+	"github.com/zentooo/logspan/formatter"
 )
 
 // PasswordMaskingMiddleware creates a middleware that masks password-related information
-// in log entries. It searches for password patterns in both message and fields.
+// in log entries. It searches for password patterns in messages, and masks the values of
+// password keys in line fields and context values, including nested maps, structs and slices.
+// With PII detectors (see WithDetectors), personal data such as emails and card numbers is
+// also replaced in messages, field values and context values.
+//
+// Context values are only known at flush time, so they are masked by OutputMiddleware, not by
+// Middleware. Use AddPasswordMasking to register both.
 type PasswordMaskingMiddleware struct {
 	// MaskString is the string used to replace password values (default: "***")
 	MaskString string
@@ -16,6 +28,12 @@ type PasswordMaskingMiddleware struct {
 
 	// PasswordPatterns are regex patterns to detect passwords in messages
 	PasswordPatterns []*regexp.Regexp
+
+	// Detectors find personal data in strings, applied in order (default: none)
+	Detectors []*PIIDetector
+
+	// HashSalt is the key of the hashes written by detectors with the MaskHash strategy
+	HashSalt string
 }
 
 // NewPasswordMaskingMiddleware creates a new password masking middleware with default settings
@@ -61,17 +79,118 @@ func (pmm *PasswordMaskingMiddleware) AddPasswordPattern(pattern *regexp.Regexp)
 	return pmm
 }
 
+// WithDetectors sets the PII detectors
+//
+// Usage:
+//
+//	masker := logger.NewPasswordMaskingMiddleware().
+//	    WithDetectors(logger.DefaultPIIDetectors()...).
+//	    WithHashSalt(os.Getenv("LOG_HASH_SALT"))
+func (pmm *PasswordMaskingMiddleware) WithDetectors(detectors ...*PIIDetector) *PasswordMaskingMiddleware {
+	pmm.Detectors = detectors
+	return pmm
+}
+
+// AddDetector adds a PII detector to the existing list
+func (pmm *PasswordMaskingMiddleware) AddDetector(detector *PIIDetector) *PasswordMaskingMiddleware {
+	pmm.Detectors = append(pmm.Detectors, detector)
+	return pmm
+}
+
+// WithHashSalt sets the key of the hashes written by the MaskHash strategy
+// Keep the salt secret: short values such as IP addresses can be recovered from unsalted hashes.
+func (pmm *PasswordMaskingMiddleware) WithHashSalt(salt string) *PasswordMaskingMiddleware {
+	pmm.HashSalt = salt
+	return pmm
+}
+
 // Middleware returns the middleware function
+// It masks the message and the fields of each line, including nested maps, structs and
+// slices, as it is logged. Context values are not masked; see OutputMiddleware.
 func (pmm *PasswordMaskingMiddleware) Middleware() Middleware {
 	return func(entry *LogEntry, next func(*LogEntry)) {
-		// Mask passwords in message
-		entry.Message = pmm.maskPasswordsInMessage(entry.Message)
+		// Mask passwords and personal data in message
+		entry.Message = pmm.maskMessage(entry.Message)
+
+		// Mask password keys in fields
+		if masked, changed := pmm.maskFields(entry.Fields); changed {
+			entry.Fields = masked
+		}
 
 		// Continue to next middleware
 		next(entry)
 	}
 }
 
+// OutputMiddleware returns the output middleware function
+// It masks the context values and the messages and fields of all lines when the document is
+// written, so that values added with AddContextValue after the lines were logged are covered.
+//
+// Usage:
+//
+//	logger.AddOutputMiddleware(logger.NewPasswordMaskingMiddleware().OutputMiddleware())
+func (pmm *PasswordMaskingMiddleware) OutputMiddleware() OutputMiddleware {
+	return func(output *formatter.LogOutput, next func(*formatter.LogOutput)) {
+		// Mask password keys and personal data in context
+		if masked, changed := pmm.maskFields(output.Context); changed {
+			output.Context = masked
+		}
+
+		// Mask lines, copying the ones that change
+		for i, line := range output.Runtime.Lines {
+			message := pmm.maskMessage(line.Message)
+			fields, changed := pmm.maskFields(line.Fields)
+			if message == line.Message && !changed {
+				continue
+			}
+			masked := *line
+			masked.Message = message
+			masked.Fields = fields
+			output.Runtime.Lines[i] = &masked
+		}
+
+		// Continue to next middleware
+		next(output)
+	}
+}
+
+// AddPasswordMasking adds the masker to the global chains: its Middleware to the middleware
+// chain and its OutputMiddleware to the output middleware chain, so that messages, line
+// fields and context values are all masked
+//
+// Usage:
+//
+//	logger.AddPasswordMasking(logger.NewPasswordMaskingMiddleware())
+func AddPasswordMasking(pmm *PasswordMaskingMiddleware) {
+	AddMiddleware(pmm.Middleware())
+	AddOutputMiddleware(pmm.OutputMiddleware())
+}
+
+// maskMessage masks password patterns and personal data in the message string
+// Values found by detectors with the MaskDrop strategy are removed from the message
+func (pmm *PasswordMaskingMiddleware) maskMessage(message string) string {
+	masked, _ := pmm.detectPII(pmm.maskPasswordsInMessage(message))
+	return masked
+}
+
+// detectPII replaces the values found by the detectors in the string
+// drop is true if a detector with the MaskDrop strategy matched
+func (pmm *PasswordMaskingMiddleware) detectPII(value string) (masked string, drop bool) {
+	masked = value
+	for _, detector := range pmm.Detectors {
+		masked = detector.Pattern.ReplaceAllStringFunc(masked, func(match string) string {
+			if detector.Validate != nil && !detector.Validate(match) {
+				return match
+			}
+			if detector.Strategy == MaskDrop {
+				drop = true
+			}
+			return detector.replace(match, pmm.MaskString, pmm.HashSalt)
+		})
+	}
+	return masked, drop
+}
+
 // maskPasswordsInMessage masks password patterns in the message string
 func (pmm *PasswordMaskingMiddleware) maskPasswordsInMessage(message string) string {
 	result := message
@@ -111,3 +230,151 @@ func (pmm *PasswordMaskingMiddleware) isPasswordKey(key string) bool {
 	}
 	return false
 }
+
+// maskFields masks the values of password keys in the fields and the structured values below
+// them. The fields are not modified; if something is masked, a masked copy is returned and
+// changed is true
+func (pmm *PasswordMaskingMiddleware) maskFields(fields map[string]interface{}) (masked map[string]interface{}, changed bool) {
+	for key, value := range fields {
+		var newValue interface{}
+		if pmm.isPasswordKey(key) {
+			newValue = pmm.MaskString
+		} else {
+			var valueChanged bool
+			if newValue, valueChanged = pmm.maskValue(value); !valueChanged {
+				continue
+			}
+		}
+
+		if !changed {
+			masked = make(map[string]interface{}, len(fields))
+			for k, v := range fields {
+				masked[k] = v
+			}
+			changed = true
+		}
+		if _, drop := newValue.(droppedValue); drop {
+			delete(masked, key)
+			continue
+		}
+		masked[key] = newValue
+	}
+
+	if !changed {
+		return fields, false
+	}
+	return masked, true
+}
+
+// droppedValue is returned by maskValue for values removed by the MaskDrop strategy
+type droppedValue struct{}
+
+// maskValue masks personal data in strings, and password keys in maps, structs and slices.
+// Password patterns are only searched in messages: a string value is masked by its key, not
+// by its content. Integers are checked by the detectors as well (card numbers stored as
+// numbers). Structs and other structured types are converted to their JSON representation only
+// if something in them is masked; otherwise the value is returned unchanged
+func (pmm *PasswordMaskingMiddleware) maskValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil, bool, float32, float64, []byte, error:
+		return value, false
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return pmm.maskNumber(value)
+	case string:
+		return pmm.maskString(v)
+	case map[string]interface{}:
+		return pmm.maskFields(v)
+	case []interface{}:
+		return pmm.maskSlice(v)
+	default:
+		return pmm.maskStructured(value)
+	}
+}
+
+// maskNumber applies the detectors to the decimal representation of an integer
+// The original value is returned if nothing is masked
+func (pmm *PasswordMaskingMiddleware) maskNumber(value interface{}) (interface{}, bool) {
+	if len(pmm.Detectors) == 0 {
+		return value, false
+	}
+	masked, changed := pmm.maskString(fmt.Sprint(value))
+	if !changed {
+		return value, false
+	}
+	return masked, true
+}
+
+// maskSlice masks the items of the slice, removing dropped items
+// The slice is not modified; if something is masked, a masked copy is returned
+func (pmm *PasswordMaskingMiddleware) maskSlice(items []interface{}) (interface{}, bool) {
+	var masked []interface{}
+	for i, item := range items {
+		newItem, changed := pmm.maskValue(item)
+		if changed && masked == nil {
+			masked = make([]interface{}, 0, len(items))
+			masked = append(masked, items[:i]...)
+		}
+		if masked == nil {
+			continue
+		}
+		if _, drop := newItem.(droppedValue); !drop {
+			masked = append(masked, newItem)
+		}
+	}
+	if masked == nil {
+		return items, false
+	}
+	return masked, true
+}
+
+// maskStructured masks maps, slices, structs and pointers of other types through their JSON
+// representation. The original value is returned if nothing is masked
+func (pmm *PasswordMaskingMiddleware) maskStructured(value interface{}) (interface{}, bool) {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr, reflect.Interface:
+		generic, ok := toGenericValue(value)
+		if !ok {
+			return value, false
+		}
+		masked, changed := pmm.maskValue(generic)
+		if !changed {
+			return value, false
+		}
+		return masked, true
+	default:
+		return value, false
+	}
+}
+
+// maskString applies the detectors to value
+// It returns droppedValue if a detector with the MaskDrop strategy matched
+func (pmm *PasswordMaskingMiddleware) maskString(value string) (interface{}, bool) {
+	masked, drop := pmm.detectPII(value)
+	if drop {
+		return droppedValue{}, true
+	}
+	return masked, masked != value
+}
+
+// toGenericValue converts a value to maps, slices and scalars through its JSON representation
+func toGenericValue(value interface{}) (interface{}, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, false
+	}
+
+	// Avoid infinite recursion on values that encode to themselves
+	switch generic.(type) {
+	case map[string]interface{}, []interface{}, string:
+		return generic, true
+	default:
+		return nil, false
+	}
+}
@@ -1,9 +1,12 @@
 package logger
 
 import (
+	"bytes"
 	"regexp"
 	"testing"
 	"time"
+
+	"github.com/zentooo/logspan/formatter"
 )
 
 func TestPasswordMaskingMiddleware_NewPasswordMaskingMiddleware(t *testing.T) {
@@ -255,3 +258,184 @@ func TestPasswordMaskingMiddleware_MessageOnly(t *testing.T) {
 		t.Errorf("Expected message to be '%s', got '%s'", expectedMessage, processedEntry.Message)
 	}
 }
+
+func TestPasswordMaskingMiddleware_MaskFields(t *testing.T) {
+	pmm := NewPasswordMaskingMiddleware()
+
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+
+	fields := map[string]interface{}{
+		"user":     "john",
+		"Password": "secret123",
+		"nested": map[string]interface{}{
+			"API_KEY": "abc",
+			"deeper":  map[string]string{"token": "xyz", "region": "eu"},
+		},
+		"list":  []interface{}{map[string]interface{}{"secret": "s1"}, "plain"},
+		"creds": &credentials{User: "john", Password: "hunter2"},
+		"count": 3,
+	}
+
+	masked, changed := pmm.maskFields(fields)
+	if !changed {
+		t.Fatal("Expected fields to be masked")
+	}
+
+	if masked["user"] != "john" || masked["count"] != 3 {
+		t.Errorf("Expected other fields to be kept, got %v", masked)
+	}
+	if masked["Password"] != "***" {
+		t.Errorf("Expected Password to be masked case-insensitively, got %v", masked["Password"])
+	}
+
+	nested := masked["nested"].(map[string]interface{})
+	if nested["API_KEY"] != "***" {
+		t.Errorf("Expected nested API_KEY to be masked, got %v", nested["API_KEY"])
+	}
+	deeper := nested["deeper"].(map[string]interface{})
+	if deeper["token"] != "***" || deeper["region"] != "eu" {
+		t.Errorf("Expected typed nested map to be masked, got %v", deeper)
+	}
+
+	list := masked["list"].([]interface{})
+	if list[0].(map[string]interface{})["secret"] != "***" || list[1] != "plain" {
+		t.Errorf("Expected slice elements to be masked, got %v", list)
+	}
+
+	creds := masked["creds"].(map[string]interface{})
+	if creds["password"] != "***" || creds["user"] != "john" {
+		t.Errorf("Expected struct to be masked, got %v", creds)
+	}
+
+	// The original fields are not modified
+	if fields["Password"] != "secret123" || fields["nested"].(map[string]interface{})["API_KEY"] != "abc" {
+		t.Errorf("Expected original fields to be unchanged, got %v", fields)
+	}
+	if fields["list"].([]interface{})[0].(map[string]interface{})["secret"] != "s1" {
+		t.Errorf("Expected original slice to be unchanged, got %v", fields["list"])
+	}
+}
+
+func TestPasswordMaskingMiddleware_MaskFieldsUnchanged(t *testing.T) {
+	pmm := NewPasswordMaskingMiddleware()
+
+	type info struct {
+		Name string
+	}
+	value := &info{Name: "john"}
+	fields := map[string]interface{}{"user": value, "when": time.Now()}
+
+	masked, changed := pmm.maskFields(fields)
+	if changed {
+		t.Errorf("Expected no change, got %v", masked)
+	}
+	if masked["user"] != value {
+		t.Error("Expected unmasked struct to be kept as is")
+	}
+}
+
+func TestPasswordMaskingMiddleware_MiddlewareFields(t *testing.T) {
+	pmm := NewPasswordMaskingMiddleware()
+
+	entry := &LogEntry{
+		Level:   "INFO",
+		Message: "login",
+		Fields:  map[string]interface{}{"user": "john", "password": "secret123"},
+	}
+
+	var processedEntry *LogEntry
+	pmm.Middleware()(entry, func(e *LogEntry) {
+		processedEntry = e
+	})
+
+	if processedEntry.Fields["password"] != "***" || processedEntry.Fields["user"] != "john" {
+		t.Errorf("Expected password field to be masked, got %v", processedEntry.Fields)
+	}
+}
+
+func TestPasswordMaskingMiddleware_OutputMiddleware(t *testing.T) {
+	pmm := NewPasswordMaskingMiddleware()
+
+	lineFields := map[string]interface{}{"token": "abc"}
+	context := map[string]interface{}{"user": map[string]interface{}{"name": "john", "pwd": "x"}}
+	output := &formatter.LogOutput{
+		Context: context,
+		Runtime: formatter.RuntimeInfo{
+			Lines: []*formatter.LogEntry{
+				{Level: "INFO", Message: "password=secret123", Fields: lineFields},
+				{Level: "INFO", Message: "plain"},
+			},
+		},
+	}
+	firstLine := output.Runtime.Lines[0]
+
+	var processed *formatter.LogOutput
+	pmm.OutputMiddleware()(output, func(o *formatter.LogOutput) {
+		processed = o
+	})
+
+	user := processed.Context["user"].(map[string]interface{})
+	if user["pwd"] != "***" || user["name"] != "john" {
+		t.Errorf("Expected nested context value to be masked, got %v", user)
+	}
+	line := processed.Runtime.Lines[0]
+	if line.Message != "password=***" || line.Fields["token"] != "***" {
+		t.Errorf("Expected line to be masked, got %+v", line)
+	}
+	if processed.Runtime.Lines[1].Message != "plain" {
+		t.Errorf("Expected plain line to be kept, got %+v", processed.Runtime.Lines[1])
+	}
+
+	// The inputs are not modified
+	if context["user"].(map[string]interface{})["pwd"] != "x" || lineFields["token"] != "abc" || firstLine.Message != "password=secret123" {
+		t.Error("Expected the original values to be unchanged")
+	}
+}
+
+func TestAddPasswordMasking(t *testing.T) {
+	ClearMiddleware()
+	ClearOutputMiddleware()
+	defer ClearMiddleware()
+	defer ClearOutputMiddleware()
+	AddPasswordMasking(NewPasswordMaskingMiddleware())
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(WithContextOutput(&buf))
+	contextLogger.Infof("request started")
+	// Added after the line was logged
+	contextLogger.AddContextValue("session", map[string]interface{}{"Access_Token": "abc", "scheme": "Bearer"})
+	contextLogger.Flush()
+
+	output := decodeOutput(t, &buf)
+	if output.Runtime.Lines[0].Message != "request started" || GetMiddlewareCount() != 1 {
+		t.Errorf("Expected the line middleware to be registered too, got %+v", output.Runtime.Lines[0])
+	}
+	session := output.Context["session"].(map[string]interface{})
+	if session["Access_Token"] != "***" || session["scheme"] != "Bearer" {
+		t.Errorf("Expected session context value to be masked, got %v", session)
+	}
+}
+
+func TestPasswordMaskingMiddleware_ValuesMaskedByKeyOnly(t *testing.T) {
+	pmm := NewPasswordMaskingMiddleware()
+
+	fields := map[string]interface{}{
+		"url":        "/search?monkey=banana&author=x",
+		"user_agent": "curl/8.0 (passkey: none)",
+		"note":       "password=secret123",
+		"token":      "abc",
+	}
+
+	masked, changed := pmm.maskFields(fields)
+	if !changed || masked["token"] != "***" {
+		t.Errorf("Expected the token key to be masked, got %v", masked)
+	}
+	for _, key := range []string{"url", "user_agent", "note"} {
+		if masked[key] != fields[key] {
+			t.Errorf("Expected %s to be kept as %q, got %q", key, fields[key], masked[key])
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaskStrategy defines how a value found by a PIIDetector is replaced
+type MaskStrategy int
+
+const (
+	// MaskFull replaces the value with the mask string
+	MaskFull MaskStrategy = iota
+	// MaskPartial keeps the last characters of the value without spaces and dashes, e.g. "****1234"
+	MaskPartial
+	// MaskHash replaces the value with a salted hash, e.g. "hash:3f1c9a0b7e2d4c61",
+	// so that the same value can still be correlated across logs
+	MaskHash
+	// MaskDrop removes the field holding the value; in messages the value is removed
+	MaskDrop
+)
+
+// Names of the built-in PII detectors
+const (
+	PIIEmail        = "email"
+	PIICreditCard   = "credit_card"
+	PIIPhone        = "phone"
+	PIIIBAN         = "iban"
+	PIIJWT          = "jwt"
+	PIIAWSAccessKey = "aws_access_key"
+	PIIIPAddress    = "ip_address"
+)
+
+// defaultVisibleChars is the default number of trailing characters kept by MaskPartial
+const defaultVisibleChars = 4
+
+// PIIDetector finds personal or secret data in strings
+// Pattern finds candidates and Validate, if set, rejects candidates that only look like the
+// data (for example numbers that fail the Luhn check). Matches are replaced according to Strategy.
+type PIIDetector struct {
+	// Name identifies the detector
+	Name string
+
+	// Pattern finds candidate values
+	Pattern *regexp.Regexp
+
+	// Validate reports whether a candidate is really the data; nil accepts every candidate
+	Validate func(match string) bool
+
+	// Strategy is the replacement strategy of the matches
+	Strategy MaskStrategy
+
+	// VisibleChars is the number of trailing characters kept by MaskPartial
+	VisibleChars int
+}
+
+// NewPIIDetector creates a detector that replaces matches of the pattern with the given strategy
+func NewPIIDetector(name string, pattern *regexp.Regexp, strategy MaskStrategy) *PIIDetector {
+	return &PIIDetector{
+		Name:         name,
+		Pattern:      pattern,
+		Strategy:     strategy,
+		VisibleChars: defaultVisibleChars,
+	}
+}
+
+// WithStrategy sets the replacement strategy of the detector
+func (d *PIIDetector) WithStrategy(strategy MaskStrategy) *PIIDetector {
+	d.Strategy = strategy
+	return d
+}
+
+// WithValidator sets the function that confirms candidate matches
+func (d *PIIDetector) WithValidator(validate func(match string) bool) *PIIDetector {
+	d.Validate = validate
+	return d
+}
+
+// WithVisibleChars sets the number of trailing characters kept by MaskPartial
+func (d *PIIDetector) WithVisibleChars(count int) *PIIDetector {
+	d.VisibleChars = count
+	return d
+}
+
+// NewEmailDetector creates a detector for email addresses (default strategy: MaskFull)
+func NewEmailDetector() *PIIDetector {
+	return NewPIIDetector(PIIEmail,
+		regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		MaskFull)
+}
+
+// NewCreditCardDetector creates a detector for card numbers of 13 to 19 digits, optionally
+// separated by spaces or dashes. Numbers that fail the Luhn check, such as most order IDs,
+// are left alone (default strategy: MaskPartial)
+func NewCreditCardDetector() *PIIDetector {
+	return NewPIIDetector(PIICreditCard,
+		regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		MaskPartial).WithValidator(isLuhnValid)
+}
+
+// NewPhoneDetector creates a detector for phone numbers in international format
+// (+81312345678), with a country code and separators (+1 555-123-4567) or with an area code
+// in parentheses ((03) 1234-5678). Numbers without a leading "+" or parentheses, such as
+// "10 000 000", are left alone (default strategy: MaskPartial)
+func NewPhoneDetector() *PIIDetector {
+	return NewPIIDetector(PIIPhone,
+		regexp.MustCompile(`\+\d{8,15}\b|\+\d{1,3}[ .\-]?(?:\(\d{1,4}\)[ .\-]?|\d{1,4}[ .\-])\d{2,4}[ .\-]\d{3,4}\b|\(\d{2,4}\)[ .\-]?\d{3,4}[ .\-]\d{3,4}\b`),
+		MaskPartial).WithValidator(isPhoneNumber)
+}
+
+// NewIBANDetector creates a detector for IBANs, validated with the ISO 13616 checksum
+// (default strategy: MaskPartial)
+func NewIBANDetector() *PIIDetector {
+	return NewPIIDetector(PIIIBAN,
+		regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		MaskPartial).WithValidator(isIBANValid)
+}
+
+// NewJWTDetector creates a detector for JSON Web Tokens (default strategy: MaskFull)
+func NewJWTDetector() *PIIDetector {
+	return NewPIIDetector(PIIJWT,
+		regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`),
+		MaskFull)
+}
+
+// NewAWSAccessKeyDetector creates a detector for AWS access key IDs (default strategy: MaskFull)
+func NewAWSAccessKeyDetector() *PIIDetector {
+	return NewPIIDetector(PIIAWSAccessKey,
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`),
+		MaskFull)
+}
+
+// NewIPAddressDetector creates a detector for IPv4 and IPv6 addresses (default strategy: MaskHash)
+// The port of an address such as "192.0.2.1:8080" is kept. Version strings with four numeric
+// parts, such as "1.2.3.4", cannot be told apart from IPv4 addresses and are hashed as well;
+// leave this detector out if logs carry such versions.
+func NewIPAddressDetector() *PIIDetector {
+	return NewPIIDetector(PIIIPAddress,
+		regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|[0-9A-Za-z:]*:[0-9A-Za-z:]*:[0-9A-Za-z:]*`),
+		MaskHash).WithValidator(isIPAddress)
+}
+
+// DefaultPIIDetectors returns new instances of all built-in detectors
+// The order matters: a value replaced by a detector is not seen by the following ones.
+func DefaultPIIDetectors() []*PIIDetector {
+	return []*PIIDetector{
+		NewJWTDetector(),
+		NewAWSAccessKeyDetector(),
+		NewEmailDetector(),
+		NewIBANDetector(),
+		NewIPAddressDetector(),
+		NewCreditCardDetector(),
+		NewPhoneDetector(),
+	}
+}
+
+// replace returns the replacement of a match according to the strategy of the detector
+func (d *PIIDetector) replace(match, maskString, salt string) string {
+	switch d.Strategy {
+	case MaskPartial:
+		// Separators of card, phone and IBAN numbers are not counted
+		visible := []rune(strings.NewReplacer(" ", "", "-", "").Replace(match))
+		if d.VisibleChars <= 0 || len(visible) <= d.VisibleChars {
+			return maskString
+		}
+		return "****" + string(visible[len(visible)-d.VisibleChars:])
+	case MaskHash:
+		mac := hmac.New(sha256.New, []byte(salt))
+		mac.Write([]byte(match))
+		return "hash:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	case MaskDrop:
+		return ""
+	default:
+		return maskString
+	}
+}
+
+// isLuhnValid reports whether the digits of the value pass the Luhn check
+func isLuhnValid(value string) bool {
+	sum := 0
+	count := 0
+	double := false
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+		count++
+	}
+	return count >= 13 && count <= 19 && sum%10 == 0
+}
+
+// isIBANValid reports whether the value passes the ISO 13616 mod-97 check
+func isIBANValid(value string) bool {
+	iban := strings.ReplaceAll(value, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	// Move the country code and check digits to the end and convert letters to numbers
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			digits.WriteString(strconv.Itoa(int(c-'A') + 10))
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// isPhoneNumber reports whether the value has the 8 to 15 digits of an E.164 phone number
+func isPhoneNumber(value string) bool {
+	count := 0
+	for _, c := range value {
+		if c >= '0' && c <= '9' {
+			count++
+		}
+	}
+	return count >= 8 && count <= 15
+}
+
+// isIPAddress reports whether the value is an IPv4 or IPv6 address
+func isIPAddress(value string) bool {
+	return net.ParseIP(value) != nil
+}
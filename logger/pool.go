@@ -37,6 +37,9 @@ func putLogEntry(entry *LogEntry) {
 	entry.Funcname = ""
 	entry.Filename = ""
 	entry.Fileline = 0
+	entry.Fields = nil
+	entry.SpanID = ""
+	entry.Truncated = false
 
 	logEntryPool.Put(entry)
 }
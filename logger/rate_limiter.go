@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRateWindows is the number of templates tracked before expired windows are pruned
+const maxRateWindows = 1024
+
+// rateLimiter allows the first burst messages per template in each interval and counts the
+// suppressed ones. At the end of an interval with suppressed messages, summary is called
+// once with their count and highest level.
+type rateLimiter struct {
+	burst    int
+	interval time.Duration
+	summary  func(level LogLevel, template string, suppressed int)
+
+	mutex   sync.Mutex
+	windows map[string]*rateWindow
+}
+
+// rateWindow tracks the messages of a template in the current interval
+type rateWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+	level      LogLevel // Highest level of the suppressed messages
+}
+
+// newRateLimiter creates a rate limiter that reports suppressed messages to summary
+func newRateLimiter(burst int, interval time.Duration, summary func(level LogLevel, template string, suppressed int)) *rateLimiter {
+	return &rateLimiter{
+		burst:    burst,
+		interval: interval,
+		summary:  summary,
+		windows:  make(map[string]*rateWindow),
+	}
+}
+
+// allow reports whether a message with the template may be written, and counts it otherwise
+func (r *rateLimiter) allow(template string, level LogLevel) bool {
+	now := time.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	window, ok := r.windows[template]
+	// A window with suppressed messages ends when its summary is written
+	if !ok || (window.suppressed == 0 && now.Sub(window.start) >= r.interval) {
+		if !ok && len(r.windows) >= maxRateWindows {
+			r.prune(now)
+		}
+		window = &rateWindow{start: now}
+		r.windows[template] = window
+	}
+
+	if window.count < r.burst {
+		window.count++
+		return true
+	}
+
+	if window.suppressed == 0 {
+		time.AfterFunc(window.start.Add(r.interval).Sub(now), func() {
+			r.endWindow(template, window)
+		})
+	}
+	window.suppressed++
+	window.level = GetHigherLevel(window.level, level)
+	return false
+}
+
+// endWindow removes the window and reports its suppressed messages
+func (r *rateLimiter) endWindow(template string, window *rateWindow) {
+	r.mutex.Lock()
+	if r.windows[template] == window {
+		delete(r.windows, template)
+	}
+	suppressed, level := window.suppressed, window.level
+	r.mutex.Unlock()
+
+	r.summary(level, template, suppressed)
+}
+
+// prune removes the expired windows without suppressed messages
+// This method assumes the mutex is already held by the caller
+func (r *rateLimiter) prune(now time.Time) {
+	for template, window := range r.windows {
+		if window.suppressed == 0 && now.Sub(window.start) >= r.interval {
+			delete(r.windows, template)
+		}
+	}
+}
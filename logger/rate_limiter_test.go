@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// recordedLines returns the lines received by the sink so far
+func recordedLines(s *recordingSink) []*formatter.LogEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var lines []*formatter.LogEntry
+	for _, output := range s.outputs {
+		lines = append(lines, output.Runtime.Lines...)
+	}
+	return lines
+}
+
+// waitForLines waits until the sink has received count lines
+func waitForLines(t *testing.T, s *recordingSink, count int) []*formatter.LogEntry {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		lines := recordedLines(s)
+		if len(lines) >= count || time.Now().After(deadline) {
+			return lines
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDirectLogger_RateLimit(t *testing.T) {
+	sink := &recordingSink{}
+	directLogger := NewDirectLogger()
+	directLogger.SetSink(sink)
+	directLogger.SetRateLimit(2, 50*time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		directLogger.Errorf("connection to %s failed (attempt %d)", "db", i)
+	}
+	directLogger.Warnf("connection to %s failed (attempt %d)", "db", 5)
+	directLogger.Infof("other template %d", 1)
+
+	if lines := recordedLines(sink); len(lines) != 3 {
+		t.Fatalf("Expected 2 limited lines and 1 other line before the summary, got %d", len(lines))
+	}
+
+	lines := waitForLines(t, sink, 4)
+	if len(lines) != 4 {
+		t.Fatalf("Expected a summary line, got %d lines", len(lines))
+	}
+
+	summary := lines[3]
+	if summary.Message != "suppressed 4 similar messages" {
+		t.Errorf("Unexpected summary message %q", summary.Message)
+	}
+	if summary.Level != "ERROR" {
+		t.Errorf("Expected the highest suppressed level, got %s", summary.Level)
+	}
+	if summary.Fields["template"] != "connection to %s failed (attempt %d)" || summary.Fields["suppressed"] != 4 {
+		t.Errorf("Unexpected summary fields %v", summary.Fields)
+	}
+
+	// After the interval, messages are allowed again
+	directLogger.Errorf("connection to %s failed (attempt %d)", "db", 6)
+	if lines := recordedLines(sink); len(lines) != 5 {
+		t.Errorf("Expected the message to be allowed in a new interval, got %d lines", len(lines))
+	}
+}
+
+func TestDirectLogger_RateLimitStructured(t *testing.T) {
+	sink := &recordingSink{}
+	directLogger := NewDirectLogger()
+	directLogger.SetSink(sink)
+	directLogger.SetRateLimit(1, time.Hour)
+
+	directLogger.Infow("cache miss", "key", "a")
+	directLogger.Infow("cache miss", "key", "b")
+	directLogger.Infow("cache hit", "key", "a")
+
+	lines := recordedLines(sink)
+	if len(lines) != 2 || lines[0].Message != "cache miss" || lines[1].Message != "cache hit" {
+		t.Errorf("Expected one line per message, got %d lines", len(lines))
+	}
+}
+
+func TestDirectLogger_RateLimitDisabled(t *testing.T) {
+	sink := &recordingSink{}
+	directLogger := NewDirectLogger()
+	directLogger.SetSink(sink)
+	directLogger.SetRateLimit(1, time.Hour)
+	directLogger.SetRateLimit(0, 0)
+
+	for i := 0; i < 3; i++ {
+		directLogger.Infof("message %d", i)
+	}
+	if lines := recordedLines(sink); len(lines) != 3 {
+		t.Errorf("Expected every line without limit, got %d", len(lines))
+	}
+}
+
+func TestInit_WithRateLimit(t *testing.T) {
+	Init(WithRateLimit(1, time.Hour))
+	defer Init()
+
+	directLogger := D.(*DirectLogger)
+	sink := &recordingSink{}
+	directLogger.SetSink(sink)
+
+	D.Infof("message %d", 1)
+	D.Infof("message %d", 2)
+	if lines := recordedLines(sink); len(lines) != 1 {
+		t.Errorf("Expected the global logger to be rate limited, got %d lines", len(lines))
+	}
+
+	config := GetConfig()
+	if config.RateLimitBurst != 1 || config.RateLimitInterval != time.Hour {
+		t.Errorf("Unexpected rate limit config %d %v", config.RateLimitBurst, config.RateLimitInterval)
+	}
+}
+
+func TestRateLimiter_Prune(t *testing.T) {
+	limiter := newRateLimiter(1, time.Millisecond, func(LogLevel, string, int) {})
+	start := time.Now().Add(-time.Second)
+	for i := 0; i < maxRateWindows; i++ {
+		limiter.windows[string(rune('a'+i%26))+time.Duration(i).String()] = &rateWindow{start: start, count: 1}
+	}
+
+	limiter.allow("new", InfoLevel)
+	if len(limiter.windows) != 1 {
+		t.Errorf("Expected expired windows to be pruned, got %d", len(limiter.windows))
+	}
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrorsField is the context field that holds the errors recorded with RecordError
+const ErrorsField = "errors"
+
+// maxErrorDepth limits how deep an error chain is followed, guarding against cycles
+const maxErrorDepth = 32
+
+// ErrorInfo is the structured form of an error and the errors it wraps
+type ErrorInfo struct {
+	// Message is the result of Error()
+	Message string `json:"message"`
+
+	// Type is the dynamic Go type of the error, such as "*fs.PathError"
+	Type string `json:"type"`
+
+	// Causes holds the wrapped errors, one for errors.Unwrap and several for errors.Join
+	Causes []ErrorInfo `json:"causes,omitempty"`
+}
+
+// NewErrorInfo converts an error and the chain of errors it wraps into an ErrorInfo
+// Both Unwrap() error and Unwrap() []error (as returned by errors.Join and fmt.Errorf
+// with several %w verbs) are followed.
+func NewErrorInfo(err error) ErrorInfo {
+	return newErrorInfo(err, 0)
+}
+
+// newErrorInfo converts err at the given depth of the chain
+func newErrorInfo(err error, depth int) ErrorInfo {
+	info := ErrorInfo{
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+	}
+	if depth >= maxErrorDepth {
+		return info
+	}
+
+	var causes []error
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() []error }:
+		causes = wrapped.Unwrap()
+	default:
+		if cause := errors.Unwrap(err); cause != nil {
+			causes = []error{cause}
+		}
+	}
+
+	for _, cause := range causes {
+		if cause != nil {
+			info.Causes = append(info.Causes, newErrorInfo(cause, depth+1))
+		}
+	}
+	return info
+}
+
+// RecordError appends the error and its chain to the "errors" context field. A nil error
+// is ignored. The aggregate severity is left unchanged; use RaiseSeverity to raise it.
+func (l *ContextLogger) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	info := NewErrorInfo(err)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	recorded, _ := l.fields[ErrorsField].([]ErrorInfo)
+	l.fields[ErrorsField] = append(recorded, info)
+}
+
+// RecordError records the error and its chain on the logger in the context
+// See ContextLogger.RecordError.
+//
+// Usage:
+//
+//	if err := repo.Save(ctx, user); err != nil {
+//	    logger.RecordError(ctx, err)
+//	    logger.RaiseSeverity(ctx, logger.ErrorLevel)
+//	    http.Error(w, "internal error", http.StatusInternalServerError)
+//	    return
+//	}
+func RecordError(ctx context.Context, err error) {
+	logger := FromContext(ctx)
+	logger.RecordError(err)
+}
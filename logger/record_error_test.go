@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestNewErrorInfo_WrappedChain(t *testing.T) {
+	base := &fs.PathError{Op: "open", Path: "/etc/app.conf", Err: fs.ErrNotExist}
+	err := fmt.Errorf("load config: %w", base)
+
+	info := NewErrorInfo(err)
+	if info.Message != err.Error() || info.Type != "*fmt.wrapError" {
+		t.Errorf("Unexpected top-level info: %+v", info)
+	}
+	if len(info.Causes) != 1 || info.Causes[0].Type != "*fs.PathError" {
+		t.Fatalf("Expected *fs.PathError cause, got %+v", info.Causes)
+	}
+	pathErr := info.Causes[0]
+	if len(pathErr.Causes) != 1 || pathErr.Causes[0].Message != fs.ErrNotExist.Error() {
+		t.Errorf("Expected fs.ErrNotExist at the end of the chain, got %+v", pathErr.Causes)
+	}
+}
+
+func TestNewErrorInfo_Join(t *testing.T) {
+	first := errors.New("first")
+	second := errors.New("second")
+
+	info := NewErrorInfo(errors.Join(first, nil, second))
+	if len(info.Causes) != 2 {
+		t.Fatalf("Expected 2 causes, got %+v", info.Causes)
+	}
+	if info.Causes[0].Message != "first" || info.Causes[1].Message != "second" {
+		t.Errorf("Unexpected joined causes: %+v", info.Causes)
+	}
+}
+
+// selfWrappingError wraps itself, forming a cycle
+type selfWrappingError struct{}
+
+func (e *selfWrappingError) Error() string { return "cycle" }
+func (e *selfWrappingError) Unwrap() error { return e }
+
+func TestNewErrorInfo_DepthLimit(t *testing.T) {
+	info := NewErrorInfo(&selfWrappingError{})
+
+	depth := 0
+	for len(info.Causes) > 0 {
+		info = info.Causes[0]
+		depth++
+	}
+	if depth != maxErrorDepth {
+		t.Errorf("Expected the chain to stop at depth %d, got %d", maxErrorDepth, depth)
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	Infof(ctx, "saving user")
+	RecordError(ctx, fmt.Errorf("save user: %w", errors.New("connection reset")))
+	RecordError(ctx, errors.New("second failure"))
+	RecordError(ctx, nil)
+
+	output := flushAndDecode(t, contextLogger, &buf)
+	if output.Runtime.Severity != "INFO" {
+		t.Errorf("Expected RecordError to leave severity INFO, got %s", output.Runtime.Severity)
+	}
+
+	var recorded []ErrorInfo
+	data, _ := json.Marshal(output.Context[ErrorsField])
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatalf("Failed to decode errors field: %v", err)
+	}
+	if len(recorded) != 2 {
+		t.Fatalf("Expected 2 recorded errors, got %+v", recorded)
+	}
+	if recorded[0].Message != "save user: connection reset" || len(recorded[0].Causes) != 1 {
+		t.Errorf("Unexpected first recorded error: %+v", recorded[0])
+	}
+	if recorded[1].Message != "second failure" {
+		t.Errorf("Unexpected second recorded error: %+v", recorded[1])
+	}
+}
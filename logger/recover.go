@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxStackDepth is the maximum number of frames recorded for a panic
+const maxStackDepth = 64
+
+// Field names of the line logged by LogPanic
+const (
+	PanicField = "panic"
+	StackField = "stack"
+)
+
+// StackFrame is a single frame of the stack trace recorded for a panic
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Recover recovers from a panic, logs it with LogPanic, flushes the logger from context
+// and then re-panics with the same value, so that lines collected before the panic are
+// not lost. It must be called directly by a deferred statement.
+//
+// Usage:
+//
+//	ctx = logger.WithLogger(ctx, logger.NewContextLogger())
+//	defer logger.Recover(ctx)
+func Recover(ctx context.Context) {
+	if value := recover(); value != nil {
+		LogPanic(ctx, value)
+		FlushContext(ctx)
+		panic(value)
+	}
+}
+
+// LogPanic logs a CRITICAL line for a recovered panic value using the logger from context
+// The line carries the panic value in the "panic" field and the stack trace of the
+// panicking goroutine in the "stack" field. Call it from the deferred function that
+// recovered the panic, so that the stack still contains the panicking frames.
+func LogPanic(ctx context.Context, value interface{}) {
+	logger := FromContext(ctx)
+	if !logger.isLevelEnabled(CriticalLevel) {
+		return
+	}
+
+	stack := panicStack(3)
+	fields := map[string]interface{}{
+		PanicField: fmt.Sprint(value),
+		StackField: stack,
+	}
+
+	// Report the panicking frame as the source of the line
+	var sourceInfo *SourceInfo
+	if GetConfig().EnableSourceInfo && len(stack) > 0 {
+		sourceInfo = &SourceInfo{
+			Funcname: stack[0].Function,
+			Filename: filepath.Base(stack[0].File),
+			Fileline: stack[0].Line,
+		}
+	}
+
+	logger.appendEntry(CriticalLevel, fmt.Sprintf("panic: %v", value), fields, logger.activeSpan(ctx), sourceInfo)
+}
+
+// panicStack returns the stack of the current goroutine starting at the frame that panicked
+// skip is the number of frames to skip, as for runtime.Callers. When the stack does not
+// contain a panic, all frames after skip are returned.
+func panicStack(skip int) []StackFrame {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []StackFrame
+	inRuntimePanic := false
+	for {
+		frame, more := frames.Next()
+		switch {
+		case frame.Function == "runtime.gopanic":
+			// Everything above the panic belongs to the recovery code
+			stack = stack[:0]
+			inRuntimePanic = true
+		case inRuntimePanic && strings.HasPrefix(frame.Function, "runtime."):
+			// Skip runtime helpers such as runtime.panicmem or runtime.sigpanic
+		default:
+			inRuntimePanic = false
+			stack = append(stack, StackFrame{
+				Function: frame.Function,
+				File:     frame.File,
+				Line:     frame.Line,
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRecover_LogsFlushesAndRepanics(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		defer Recover(ctx)
+
+		Infof(ctx, "working")
+		var m map[string]int
+		m["nil map"] = 1
+	}()
+
+	if recovered == nil {
+		t.Fatal("Expected Recover to re-panic")
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "working") {
+		t.Errorf("Expected lines before the panic to be flushed, got %s", out)
+	}
+	if !strings.Contains(out, "CRITICAL") || !strings.Contains(out, "assignment to entry in nil map") {
+		t.Errorf("Expected a CRITICAL panic line, got %s", out)
+	}
+	if !strings.Contains(out, "TestRecover_LogsFlushesAndRepanics") {
+		t.Errorf("Expected the stack to contain the panicking function, got %s", out)
+	}
+}
+
+func TestRecover_NoPanic(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	func() {
+		defer Recover(ctx)
+		Infof(ctx, "fine")
+	}()
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output without a panic, got %s", buf.String())
+	}
+}
+
+func TestLogPanic_StackStartsAtPanic(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	func() {
+		defer func() {
+			if value := recover(); value != nil {
+				LogPanic(ctx, value)
+			}
+		}()
+		panicHelper()
+	}()
+
+	output := flushAndDecode(t, contextLogger, &buf)
+	if len(output.Runtime.Lines) != 1 {
+		t.Fatalf("Expected 1 line, got %d", len(output.Runtime.Lines))
+	}
+	line := output.Runtime.Lines[0]
+	if line.Level != "CRITICAL" || line.Message != "panic: helper failed" {
+		t.Errorf("Unexpected panic line: %+v", line)
+	}
+	stack, ok := line.Fields[StackField].([]interface{})
+	if !ok || len(stack) == 0 {
+		t.Fatalf("Expected stack frames, got %v", line.Fields[StackField])
+	}
+	top := stack[0].(map[string]interface{})
+	if !strings.HasSuffix(top["function"].(string), "panicHelper") {
+		t.Errorf("Expected the first frame to be panicHelper, got %v", top["function"])
+	}
+	if top["line"].(float64) == 0 || !strings.HasSuffix(top["file"].(string), "recover_test.go") {
+		t.Errorf("Expected file and line of the panic, got %v", top)
+	}
+}
+
+// panicHelper panics from a named function so that the top stack frame is predictable
+func panicHelper() {
+	panic("helper failed")
+}
@@ -0,0 +1,225 @@
+package logger
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// Sampler decides at flush time whether an aggregated log is written (tail-based sampling)
+// Because a ContextLogger buffers the whole request, the decision can use the aggregate
+// severity and the elapsed time. An aggregate is kept if any of the following holds, in order:
+//
+//  1. its severity is at or above the minimum level (ERROR by default)
+//  2. it took at least the slow threshold, if set
+//  3. fewer than the per-second limit of aggregates of its type were kept in the current second, if set
+//  4. it passes the probabilistic rate (1.0 by default, which keeps everything)
+//
+// The rate decision is deterministic on the first ID found in the context (trace_id or
+// request_id by default), so all services sampling a trace keep or drop it together.
+// A Sampler is safe for concurrent use and is usually shared by all loggers.
+type Sampler struct {
+	minLevel   LogLevel
+	slowerThan time.Duration
+	perSecond  int
+	rate       float64
+	idKeys     []string
+
+	mutex   sync.Mutex
+	windows map[string]*sampleWindow // Per-second windows by type
+	kept    uint64
+	dropped map[string]uint64 // Sampled-out aggregates by type
+}
+
+// sampleWindow counts the aggregates kept in one second for the per-second limit
+type sampleWindow struct {
+	second int64
+	count  int
+}
+
+// SamplingStats holds the counters of a Sampler
+type SamplingStats struct {
+	// Kept is the number of aggregates written
+	Kept uint64
+
+	// Dropped is the number of sampled-out aggregates
+	Dropped uint64
+
+	// DroppedByType is the number of sampled-out aggregates by type
+	DroppedByType map[string]uint64
+}
+
+// SamplerOption is a function that configures a Sampler
+type SamplerOption func(*Sampler)
+
+// WithSampleMinLevel keeps every aggregate whose severity is at or above level
+// The default is ErrorLevel
+func WithSampleMinLevel(level LogLevel) SamplerOption {
+	return func(s *Sampler) {
+		s.minLevel = level
+	}
+}
+
+// WithSampleSlowerThan keeps every aggregate whose elapsed time is at least d
+// 0 (the default) disables the threshold
+func WithSampleSlowerThan(d time.Duration) SamplerOption {
+	return func(s *Sampler) {
+		s.slowerThan = d
+	}
+}
+
+// WithSamplePerSecond keeps the first n aggregates per second of each type before the rate
+// applies. 0 (the default) disables the limit
+func WithSamplePerSecond(n int) SamplerOption {
+	return func(s *Sampler) {
+		s.perSecond = n
+	}
+}
+
+// WithSampleRate keeps the given fraction (0.0 to 1.0) of the remaining aggregates
+// The default is 1.0, which keeps everything
+func WithSampleRate(rate float64) SamplerOption {
+	return func(s *Sampler) {
+		s.rate = rate
+	}
+}
+
+// WithSampleIDKeys sets the context keys whose value makes the rate decision deterministic
+// The first key present in the context is used. The default is "trace_id", "request_id"
+func WithSampleIDKeys(keys ...string) SamplerOption {
+	return func(s *Sampler) {
+		s.idKeys = keys
+	}
+}
+
+// NewSampler creates a Sampler configured with options
+//
+// Usage:
+//
+//	sampler := logger.NewSampler(
+//	    logger.WithSampleMinLevel(logger.WarnLevel),           // Keep every WARN and above
+//	    logger.WithSampleSlowerThan(500*time.Millisecond),     // Keep slow requests
+//	    logger.WithSamplePerSecond(10),                        // Keep 10 per second per type
+//	    logger.WithSampleRate(0.01),                           // And 1% of the rest
+//	)
+//	logger.Init(logger.WithSampler(sampler))
+func NewSampler(options ...SamplerOption) *Sampler {
+	s := &Sampler{
+		minLevel: ErrorLevel,
+		rate:     1.0,
+		idKeys:   []string{"trace_id", "request_id"},
+		windows:  make(map[string]*sampleWindow),
+		dropped:  make(map[string]uint64),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Sample reports whether the aggregated log is kept, and updates the counters
+func (s *Sampler) Sample(output *formatter.LogOutput) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.keep(output) {
+		s.kept++
+		return true
+	}
+	s.dropped[output.Type]++
+	return false
+}
+
+// keep applies the policy to the aggregated log
+// This method assumes the mutex is already held by the caller
+func (s *Sampler) keep(output *formatter.LogOutput) bool {
+	if IsLevelEnabled(ParseLogLevel(output.Runtime.Severity), s.minLevel) {
+		return true
+	}
+	if s.slowerThan > 0 && time.Duration(output.Runtime.Elapsed)*time.Millisecond >= s.slowerThan {
+		return true
+	}
+
+	if s.perSecond > 0 {
+		now := time.Now().Unix()
+		window, ok := s.windows[output.Type]
+		if !ok {
+			window = &sampleWindow{}
+			s.windows[output.Type] = window
+		}
+		if window.second != now {
+			window.second = now
+			window.count = 0
+		}
+		if window.count < s.perSecond {
+			window.count++
+			return true
+		}
+	}
+
+	switch {
+	case s.rate >= 1:
+		return true
+	case s.rate <= 0:
+		return false
+	}
+	if id, ok := s.sampleID(output.Context); ok {
+		return float64(hashID(id))/math.MaxUint64 < s.rate
+	}
+	return rand.Float64() < s.rate
+}
+
+// hashID hashes an ID to a uniformly distributed 64-bit value
+// FNV-1a alone distributes similar IDs poorly, so its result is mixed with the SplitMix64 finalizer
+func hashID(id string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// sampleID returns the value of the first ID key present in the context
+func (s *Sampler) sampleID(context map[string]interface{}) (string, bool) {
+	for _, key := range s.idKeys {
+		if value, ok := context[key]; ok && value != nil && value != "" {
+			return fmt.Sprint(value), true
+		}
+	}
+	return "", false
+}
+
+// Stats returns a snapshot of the counters
+func (s *Sampler) Stats() SamplingStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := SamplingStats{
+		Kept:          s.kept,
+		DroppedByType: make(map[string]uint64, len(s.dropped)),
+	}
+	for logType, count := range s.dropped {
+		stats.Dropped += count
+		stats.DroppedByType[logType] = count
+	}
+	return stats
+}
+
+// GetSamplingStats returns the counters of the global Sampler set with WithSampler
+// It returns zero counters if no sampler is configured.
+func GetSamplingStats() SamplingStats {
+	sampler := GetConfig().Sampler
+	if sampler == nil {
+		return SamplingStats{DroppedByType: map[string]uint64{}}
+	}
+	return sampler.Stats()
+}
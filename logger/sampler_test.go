@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// sampleOutput creates an aggregated log for sampler tests
+func sampleOutput(logType, severity string, elapsed int64, context map[string]interface{}) *formatter.LogOutput {
+	return &formatter.LogOutput{
+		Type:    logType,
+		Context: context,
+		Runtime: formatter.RuntimeInfo{Severity: severity, Elapsed: elapsed},
+	}
+}
+
+func TestSampler_DefaultKeepsEverything(t *testing.T) {
+	sampler := NewSampler()
+	for i := 0; i < 10; i++ {
+		if !sampler.Sample(sampleOutput("request", "DEBUG", 1, nil)) {
+			t.Fatal("Expected default sampler to keep everything")
+		}
+	}
+	if stats := sampler.Stats(); stats.Kept != 10 || stats.Dropped != 0 {
+		t.Errorf("Expected 10 kept and 0 dropped, got %+v", stats)
+	}
+}
+
+func TestSampler_KeepsSeverityAndSlow(t *testing.T) {
+	sampler := NewSampler(
+		WithSampleRate(0),
+		WithSampleMinLevel(WarnLevel),
+		WithSampleSlowerThan(500*time.Millisecond),
+	)
+
+	testCases := []struct {
+		name     string
+		output   *formatter.LogOutput
+		expected bool
+	}{
+		{"error", sampleOutput("request", "ERROR", 1, nil), true},
+		{"warn", sampleOutput("request", "WARN", 1, nil), true},
+		{"slow", sampleOutput("request", "INFO", 500, nil), true},
+		{"fast info", sampleOutput("request", "INFO", 499, nil), false},
+	}
+	for _, tc := range testCases {
+		if result := sampler.Sample(tc.output); result != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, result)
+		}
+	}
+}
+
+func TestSampler_PerSecondPerType(t *testing.T) {
+	sampler := NewSampler(WithSampleRate(0), WithSamplePerSecond(2))
+
+	// Stay within one second
+	for time.Now().Nanosecond() > 900*int(time.Millisecond) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	kept := map[string]int{}
+	for i := 0; i < 5; i++ {
+		for _, logType := range []string{"request", "batch"} {
+			if sampler.Sample(sampleOutput(logType, "INFO", 1, nil)) {
+				kept[logType]++
+			}
+		}
+	}
+
+	if kept["request"] != 2 || kept["batch"] != 2 {
+		t.Errorf("Expected 2 kept per type, got %v", kept)
+	}
+	stats := sampler.Stats()
+	if stats.Kept != 4 || stats.Dropped != 6 || stats.DroppedByType["request"] != 3 || stats.DroppedByType["batch"] != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSampler_DeterministicOnID(t *testing.T) {
+	sampler := NewSampler(WithSampleRate(0.5))
+
+	keptCount := 0
+	for i := 0; i < 200; i++ {
+		context := map[string]interface{}{"trace_id": fmt.Sprintf("trace-%d", i)}
+		first := sampler.Sample(sampleOutput("request", "INFO", 1, context))
+		for j := 0; j < 3; j++ {
+			if sampler.Sample(sampleOutput("request", "INFO", 1, context)) != first {
+				t.Fatalf("Expected the same decision for trace-%d", i)
+			}
+		}
+		if first {
+			keptCount++
+		}
+	}
+
+	// Roughly half of the traces are kept
+	if keptCount < 60 || keptCount > 140 {
+		t.Errorf("Expected about half of the traces to be kept, got %d of 200", keptCount)
+	}
+
+	// request_id is used when there is no trace_id
+	context := map[string]interface{}{"request_id": "req-1"}
+	first := sampler.Sample(sampleOutput("request", "INFO", 1, context))
+	if sampler.Sample(sampleOutput("request", "INFO", 1, context)) != first {
+		t.Error("Expected the same decision for the same request_id")
+	}
+}
+
+func TestContextLogger_Sampler(t *testing.T) {
+	sampler := NewSampler(WithSampleRate(0))
+	Init(WithSampler(sampler))
+	defer Init()
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(WithContextOutput(&buf))
+	contextLogger.Infof("sampled out")
+	contextLogger.Flush()
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected aggregate to be sampled out, got %s", buf.String())
+	}
+
+	// The discarded lines do not leak into the next flush
+	contextLogger.Errorf("kept")
+	contextLogger.Flush()
+
+	output := buf.String()
+	if strings.Contains(output, "sampled out") || !strings.Contains(output, "kept") {
+		t.Errorf("Expected only the error aggregate, got %s", output)
+	}
+
+	stats := GetSamplingStats()
+	if stats.Kept != 1 || stats.Dropped != 1 || stats.DroppedByType["request"] != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	// The option overrides the global sampler
+	buf.Reset()
+	unsampled := NewContextLoggerWithOptions(WithContextOutput(&buf), WithContextSampler(nil))
+	unsampled.Infof("written")
+	unsampled.Flush()
+	if !strings.Contains(buf.String(), "written") {
+		t.Errorf("Expected aggregate to be written without sampler, got %s", buf.String())
+	}
+}
+
+func TestGetSamplingStats_NoSampler(t *testing.T) {
+	Init()
+	defer Init()
+
+	stats := GetSamplingStats()
+	if stats.Kept != 0 || stats.Dropped != 0 || stats.DroppedByType == nil {
+		t.Errorf("Expected zero stats, got %+v", stats)
+	}
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// Sink receives log documents before formatting and decides how to output them
+// A logger with a sink passes each flushed document (a ContextLogger flush or a DirectLogger
+// line) to the sink instead of formatting it and writing it to its output.
+// The document may be shared between several sinks and must not be modified.
+type Sink interface {
+	Write(output *formatter.LogOutput) error
+}
+
+// WriterSink is a Sink that formats documents and writes them to an io.Writer
+// Documents whose aggregated severity is below the sink's minimum level are skipped.
+type WriterSink struct {
+	writer    io.Writer
+	minLevel  LogLevel
+	formatter formatter.Formatter
+	mutex     sync.Mutex
+}
+
+// SinkOption is a function that configures a WriterSink
+type SinkOption func(*WriterSink)
+
+// WithSinkLevel sets the minimum aggregated severity of the documents written by the sink
+// The default is DebugLevel, which writes every document the logger emits
+func WithSinkLevel(level LogLevel) SinkOption {
+	return func(s *WriterSink) {
+		s.minLevel = level
+	}
+}
+
+// WithSinkFormatter sets the formatter of the sink
+// The default is the JSON formatter selected by the global PrettifyJSON setting
+func WithSinkFormatter(f formatter.Formatter) SinkOption {
+	return func(s *WriterSink) {
+		s.formatter = f
+	}
+}
+
+// NewWriterSink creates a sink that formats documents and writes them to w
+//
+// Usage:
+//
+//	logger.Init(logger.WithSinks(
+//	    logger.NewWriterSink(file),
+//	    logger.NewWriterSink(os.Stderr,
+//	        logger.WithSinkLevel(logger.WarnLevel),
+//	        logger.WithSinkFormatter(formatter.NewJSONFormatterWithIndent("  "))),
+//	))
+func NewWriterSink(w io.Writer, options ...SinkOption) *WriterSink {
+	s := &WriterSink{
+		writer:   w,
+		minLevel: DebugLevel,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Write formats the document and writes it, unless its severity is below the minimum level
+func (s *WriterSink) Write(output *formatter.LogOutput) error {
+	level := ParseLogLevel(output.Runtime.Severity)
+	if !IsLevelEnabled(level, s.minLevel) {
+		return nil
+	}
+
+	records, err := formatRecordsWithLimit(output, s.formatter, GetConfig().MaxOutputBytes)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return writeRecords(s.writer, records)
+}
+
+// MultiSink is a Sink that passes each document to several sinks
+// Sinks are isolated from each other: an error or a panic in one sink does not prevent
+// the document from reaching the others. Wrap slow writers in an asynchronous writer
+// (see the sink package) so that they do not delay the other sinks.
+type MultiSink struct {
+	sinks []Sink
+}
+
+// NewMultiSink creates a sink that fans documents out to the given sinks in order
+func NewMultiSink(sinks ...Sink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+// Write passes the document to every sink and returns the errors of the failed ones
+func (m *MultiSink) Write(output *formatter.LogOutput) error {
+	var errs []error
+	for i, s := range m.sinks {
+		if err := writeToSink(s, output); err != nil {
+			errs = append(errs, fmt.Errorf("sink %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeToSink writes the document to the sink and converts a panic into an error
+func writeToSink(s Sink, output *formatter.LogOutput) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return s.Write(output)
+}
+
+// newSinkFromConfig returns the sink configured with WithSinks, or nil if there is none
+func newSinkFromConfig(sinks []Sink) Sink {
+	switch len(sinks) {
+	case 0:
+		return nil
+	case 1:
+		return sinks[0]
+	default:
+		return NewMultiSink(sinks...)
+	}
+}
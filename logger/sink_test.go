@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// recordingSink records the documents it receives
+type recordingSink struct {
+	mutex   sync.Mutex
+	outputs []*formatter.LogOutput
+	err     error
+}
+
+func (s *recordingSink) Write(output *formatter.LogOutput) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.outputs = append(s.outputs, output)
+	return s.err
+}
+
+// panickingSink panics on every write
+type panickingSink struct{}
+
+func (panickingSink) Write(*formatter.LogOutput) error {
+	panic("sink exploded")
+}
+
+func TestWriterSink_LevelAndFormatter(t *testing.T) {
+	var all, warn bytes.Buffer
+	multi := NewMultiSink(
+		NewWriterSink(&all, WithSinkFormatter(formatter.NewJSONFormatter())),
+		NewWriterSink(&warn, WithSinkLevel(WarnLevel), WithSinkFormatter(formatter.NewContextFlattenFormatter())),
+	)
+
+	contextLogger := NewContextLogger()
+	contextLogger.SetSink(multi)
+	contextLogger.AddContextValue("request_id", "req-1")
+
+	contextLogger.Infof("ok")
+	contextLogger.Flush()
+	contextLogger.Warnf("slow")
+	contextLogger.Flush()
+
+	if lines := strings.Count(all.String(), "\n"); lines != 2 {
+		t.Errorf("Expected 2 documents in the unfiltered sink, got %d: %s", lines, all.String())
+	}
+	if !strings.Contains(all.String(), `"context":{"request_id":"req-1"}`) {
+		t.Errorf("Expected nested JSON in the first sink, got %s", all.String())
+	}
+
+	if lines := strings.Count(warn.String(), "\n"); lines != 1 {
+		t.Errorf("Expected only the WARN document in the filtered sink, got %d: %s", lines, warn.String())
+	}
+	if !strings.Contains(warn.String(), `"request_id":"req-1"`) || strings.Contains(warn.String(), `"context"`) {
+		t.Errorf("Expected flattened JSON in the second sink, got %s", warn.String())
+	}
+}
+
+func TestMultiSink_IsolatesFailures(t *testing.T) {
+	failing := &recordingSink{err: errors.New("collector unavailable")}
+	healthy := &recordingSink{}
+	multi := NewMultiSink(failing, panickingSink{}, healthy)
+
+	err := multi.Write(&formatter.LogOutput{})
+	if len(healthy.outputs) != 1 {
+		t.Errorf("Expected the healthy sink to receive the document, got %d", len(healthy.outputs))
+	}
+	if err == nil || !strings.Contains(err.Error(), "sink 0: collector unavailable") ||
+		!strings.Contains(err.Error(), "sink 1: panic: sink exploded") {
+		t.Errorf("Expected errors of both failing sinks, got %v", err)
+	}
+}
+
+func TestSink_ErrorsGoToErrorHandler(t *testing.T) {
+	var reported []string
+	previous := GetGlobalErrorHandler()
+	SetGlobalErrorHandler(ErrorHandlerFunc(func(operation string, err error) {
+		reported = append(reported, operation+": "+err.Error())
+	}))
+	defer SetGlobalErrorHandler(previous)
+
+	contextLogger := NewContextLogger()
+	contextLogger.SetSink(panickingSink{})
+	contextLogger.Infof("message")
+	contextLogger.Flush()
+
+	if len(reported) != 1 || reported[0] != "sink: panic: sink exploded" {
+		t.Errorf("Expected the sink panic to be reported, got %v", reported)
+	}
+}
+
+func TestWithSinks_ContextAndDirectLoggers(t *testing.T) {
+	sink := &recordingSink{}
+	Init(WithSinks(sink), WithMinLevel(DebugLevel))
+	defer Init()
+
+	contextLogger := NewContextLogger()
+	contextLogger.Debugf("context line")
+	contextLogger.Flush()
+	D.Errorf("direct line")
+
+	if len(sink.outputs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(sink.outputs))
+	}
+	if sink.outputs[0].Runtime.Lines[0].Message != "context line" {
+		t.Errorf("Unexpected context document: %+v", sink.outputs[0].Runtime.Lines[0])
+	}
+	if sink.outputs[1].Runtime.Severity != "ERROR" || sink.outputs[1].Runtime.Lines[0].Message != "direct line" {
+		t.Errorf("Unexpected direct document: %+v", sink.outputs[1].Runtime)
+	}
+
+	// Removing the sink restores the output
+	var buf bytes.Buffer
+	contextLogger.SetSink(nil)
+	contextLogger.SetOutput(&buf)
+	contextLogger.Infof("to output")
+	contextLogger.Flush()
+	if !strings.Contains(buf.String(), "to output") || len(sink.outputs) != 2 {
+		t.Errorf("Expected the document on the output only, got %q", buf.String())
+	}
+}
+
+func TestWithSinks_PerLoggerOutputOverridesSink(t *testing.T) {
+	sink := &recordingSink{}
+	var global bytes.Buffer
+	Init(WithSinks(sink), WithOutput(&global))
+	defer Init()
+
+	var own bytes.Buffer
+	contextLogger := NewContextLoggerWithOptions(WithContextOutput(&own))
+	contextLogger.Infof("own output")
+	contextLogger.Flush()
+	if !strings.Contains(own.String(), "own output") {
+		t.Errorf("Expected the document on the logger's output, got %q", own.String())
+	}
+
+	formatted := NewContextLoggerWithOptions(WithContextFormatter(formatter.NewLogfmtFormatter()))
+	formatted.Infof("own formatter")
+	formatted.Flush()
+	if !strings.Contains(global.String(), `msg="own formatter"`) {
+		t.Errorf("Expected the document formatted by the logger's formatter, got %q", global.String())
+	}
+
+	// The last of the sink and output options applies
+	var last bytes.Buffer
+	sinkLogger := NewContextLoggerWithOptions(WithContextOutput(&last), WithContextSink(sink))
+	sinkLogger.Infof("to sink")
+	sinkLogger.Flush()
+
+	directLogger := NewDirectLogger()
+	directLogger.SetSink(sink)
+	var direct bytes.Buffer
+	directLogger.SetOutput(&direct)
+	directLogger.Infof("direct output")
+	if !strings.Contains(direct.String(), "direct output") {
+		t.Errorf("Expected SetOutput to replace the sink, got %q", direct.String())
+	}
+
+	if len(sink.outputs) != 1 || last.Len() != 0 {
+		t.Errorf("Expected only the logger with WithContextSink last on the sink, got %d documents", len(sink.outputs))
+	}
+}
+
+func TestWriterSink_RecordFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetSink(NewWriterSink(&buf, WithSinkFormatter(formatter.NewExplodedJSONFormatter())))
+	contextLogger.AddContextValue("request_id", "req-1")
+
+	contextLogger.Infof("one")
+	contextLogger.Infof("two")
+	contextLogger.Infof("three")
+	contextLogger.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 documents, got %d: %s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, `"request_id":"req-1"`) || !strings.Contains(line, fmt.Sprintf(`"seq":%d,"total":3`, i+1)) {
+			t.Errorf("Unexpected document %d: %s", i, line)
+		}
+	}
+}
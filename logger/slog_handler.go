@@ -0,0 +1,189 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+)
+
+// SlogLevelCritical is the slog level that maps onto CriticalLevel
+// Any slog level at or above this value is logged as CRITICAL
+const SlogLevelCritical = slog.LevelError + 4
+
+// SlogHandler implements slog.Handler on top of logspan loggers.
+// Records are routed into the ContextLogger stored in the context by WithLogger,
+// so that code written against log/slog contributes lines to the aggregated output.
+// When the context carries no logger, records are written through the global direct logger D.
+type SlogHandler struct {
+	// attrs are the attributes added with WithAttrs, with group prefixes already applied
+	attrs []slog.Attr
+
+	// prefix is the dotted group prefix applied to record attributes
+	prefix string
+}
+
+// NewSlogHandler creates a new SlogHandler
+//
+// Usage:
+//
+//	slog.SetDefault(slog.New(logger.NewSlogHandler()))
+//	slog.InfoContext(ctx, "user loaded", "user_id", id)
+func NewSlogHandler() *SlogHandler {
+	return &SlogHandler{}
+}
+
+// SlogLevelToLogLevel converts a slog level to the corresponding LogLevel
+func SlogLevelToLogLevel(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	case level < SlogLevelCritical:
+		return ErrorLevel
+	default:
+		return CriticalLevel
+	}
+}
+
+// Enabled reports whether the logger that would receive the record accepts the level
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	logLevel := SlogLevelToLogLevel(level)
+
+	if contextLogger, ok := loggerFromContext(ctx); ok {
+		return contextLogger.isLevelEnabled(logLevel)
+	}
+	if directLogger, ok := D.(*DirectLogger); ok {
+		return directLogger.isLevelEnabled(logLevel)
+	}
+	return true
+}
+
+// Handle adds the record as a line to the ContextLogger in the context.
+// Attributes added with WithAttrs and record attributes both become fields of the line,
+// so loggers with different attributes in the same request do not overwrite each other.
+// Records written through the direct logger D are subject to its rate limit.
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	level := SlogLevelToLogLevel(record.Level)
+
+	var sourceInfo *SourceInfo
+	if GetConfig().EnableSourceInfo && record.PC != 0 {
+		sourceInfo = sourceInfoFromPC(record.PC)
+	}
+
+	fields := h.fields(record)
+
+	if contextLogger, ok := loggerFromContext(ctx); ok {
+		contextLogger.appendEntry(level, record.Message, fields, contextLogger.activeSpan(ctx), sourceInfo)
+		return nil
+	}
+
+	if directLogger, ok := D.(*DirectLogger); ok {
+		if directLogger.enabled(level, record.Message) {
+			directLogger.writeEntry(level, record.Message, fields, sourceInfo)
+		}
+		return nil
+	}
+
+	keysAndValues := make([]interface{}, 0, len(fields)*2)
+	for k, v := range fields {
+		keysAndValues = append(keysAndValues, k, v)
+	}
+	logAtLevel(Structured(D), level, record.Message, keysAndValues...)
+	return nil
+}
+
+// fields returns the line fields of a record: the handler attributes followed by the
+// record attributes, which win on duplicate keys. It returns nil when there are none.
+func (h *SlogHandler) fields(record slog.Record) map[string]interface{} {
+	if len(h.attrs) == 0 && record.NumAttrs() == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(h.attrs)+record.NumAttrs())
+	for _, attr := range h.attrs {
+		addSlogAttr(fields, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(fields, h.prefix, attr)
+		return true
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// WithAttrs returns a new handler whose attributes consist of both the receiver's
+// attributes and the arguments
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	newAttrs := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	newAttrs = append(newAttrs, h.attrs...)
+	for _, attr := range attrs {
+		if h.prefix != "" && attr.Key != "" {
+			attr.Key = h.prefix + attr.Key
+		}
+		newAttrs = append(newAttrs, attr)
+	}
+
+	return &SlogHandler{
+		attrs:  newAttrs,
+		prefix: h.prefix,
+	}
+}
+
+// WithGroup returns a new handler that prefixes the keys of subsequent attributes
+// with the group name, separated by a dot
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &SlogHandler{
+		attrs:  h.attrs,
+		prefix: h.prefix + name + ".",
+	}
+}
+
+// addSlogAttr resolves an attribute and stores it in fields, flattening groups into dotted keys
+func addSlogAttr(fields map[string]interface{}, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			addSlogAttr(fields, groupPrefix, groupAttr)
+		}
+		return
+	}
+
+	fields[prefix+attr.Key] = attr.Value.Any()
+}
+
+// sourceInfoFromPC builds source information from a program counter
+func sourceInfoFromPC(pc uintptr) *SourceInfo {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	frame, _ := frames.Next()
+	if frame.Function == "" && frame.File == "" {
+		return &SourceInfo{}
+	}
+
+	return &SourceInfo{
+		Funcname: frame.Function,
+		Filename: filepath.Base(frame.File),
+		Fileline: frame.Line,
+	}
+}
@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogLevelToLogLevel(t *testing.T) {
+	tests := []struct {
+		level    slog.Level
+		expected LogLevel
+	}{
+		{slog.LevelDebug - 4, DebugLevel},
+		{slog.LevelDebug, DebugLevel},
+		{slog.LevelInfo, InfoLevel},
+		{slog.LevelInfo + 2, InfoLevel},
+		{slog.LevelWarn, WarnLevel},
+		{slog.LevelError, ErrorLevel},
+		{SlogLevelCritical, CriticalLevel},
+		{SlogLevelCritical + 4, CriticalLevel},
+	}
+
+	for _, tt := range tests {
+		if got := SlogLevelToLogLevel(tt.level); got != tt.expected {
+			t.Errorf("SlogLevelToLogLevel(%v) = %v, expected %v", tt.level, got, tt.expected)
+		}
+	}
+}
+
+func TestSlogHandler_RoutesToContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	log := slog.New(NewSlogHandler())
+	log.InfoContext(ctx, "first line", "user_id", 42)
+	log.WarnContext(ctx, "second line")
+	contextLogger.Flush()
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	runtimeInfo := output["runtime"].(map[string]interface{})
+	if runtimeInfo["severity"] != "WARN" {
+		t.Errorf("Expected severity WARN, got %v", runtimeInfo["severity"])
+	}
+
+	lines := runtimeInfo["lines"].([]interface{})
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	first := lines[0].(map[string]interface{})
+	if first["message"] != "first line" {
+		t.Errorf("Unexpected message: %v", first["message"])
+	}
+	fields, ok := first["fields"].(map[string]interface{})
+	if !ok || fields["user_id"] != float64(42) {
+		t.Errorf("Expected user_id=42 in line fields, got %v", first["fields"])
+	}
+	if first["level"] != "INFO" {
+		t.Errorf("Expected level INFO, got %v", first["level"])
+	}
+}
+
+func TestSlogHandler_WithAttrsAndGroup(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	log := slog.New(NewSlogHandler()).
+		With("service", "billing").
+		WithGroup("db").
+		With("table", "invoices")
+	log.InfoContext(ctx, "query executed", "rows", 3, slog.Group("timing", "ms", 12))
+	contextLogger.Flush()
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	lines := output["runtime"].(map[string]interface{})["lines"].([]interface{})
+	fields := lines[0].(map[string]interface{})["fields"].(map[string]interface{})
+	if fields["service"] != "billing" {
+		t.Errorf("Expected service=billing in line fields, got %v", fields["service"])
+	}
+	if fields["db.table"] != "invoices" {
+		t.Errorf("Expected db.table=invoices in line fields, got %v", fields["db.table"])
+	}
+	if fields["db.rows"] != float64(3) {
+		t.Errorf("Expected db.rows=3 in line fields, got %v", fields["db.rows"])
+	}
+	if fields["db.timing.ms"] != float64(12) {
+		t.Errorf("Expected db.timing.ms=12 in line fields, got %v", fields["db.timing.ms"])
+	}
+	if context, ok := output["context"].(map[string]interface{}); ok {
+		if _, exists := context["service"]; exists {
+			t.Error("Handler attributes should not be added to the request context")
+		}
+	}
+}
+
+func TestSlogHandler_WithAttrsPerLogger(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	handler := NewSlogHandler()
+	slog.New(handler).With("component", "api").InfoContext(ctx, "request parsed")
+	slog.New(handler).With("component", "db").InfoContext(ctx, "query executed")
+	contextLogger.Flush()
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	lines := output["runtime"].(map[string]interface{})["lines"].([]interface{})
+	for i, want := range []string{"api", "db"} {
+		fields := lines[i].(map[string]interface{})["fields"].(map[string]interface{})
+		if fields["component"] != want {
+			t.Errorf("Expected component=%s on line %d, got %v", want, i, fields["component"])
+		}
+	}
+}
+
+func TestSlogHandler_Enabled(t *testing.T) {
+	contextLogger := NewContextLogger()
+	contextLogger.SetLevel(WarnLevel)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	handler := NewSlogHandler()
+	if handler.Enabled(ctx, slog.LevelInfo) {
+		t.Error("Expected INFO to be disabled for a WARN logger")
+	}
+	if !handler.Enabled(ctx, slog.LevelError) {
+		t.Error("Expected ERROR to be enabled for a WARN logger")
+	}
+}
+
+func TestSlogHandler_FallbackToDirectLogger(t *testing.T) {
+	var buf bytes.Buffer
+	directLogger := D.(*DirectLogger)
+	originalOutput := directLogger.getOutput()
+	directLogger.SetOutput(&buf)
+	defer directLogger.SetOutput(originalOutput)
+
+	log := slog.New(NewSlogHandler()).With("component", "worker")
+	log.ErrorContext(context.Background(), "job failed", "attempt", 2)
+
+	output := buf.String()
+	if !strings.Contains(output, `"ERROR"`) {
+		t.Errorf("Expected ERROR line from direct logger, got %s", output)
+	}
+	if !strings.Contains(output, "job failed") {
+		t.Errorf("Expected message in output, got %s", output)
+	}
+	if !strings.Contains(output, `"component"`) || !strings.Contains(output, `"attempt"`) {
+		t.Errorf("Expected handler and record attributes in line fields, got %s", output)
+	}
+}
+
+func TestSlogHandler_DirectLoggerRateLimit(t *testing.T) {
+	sink := &recordingSink{}
+	directLogger := D.(*DirectLogger)
+	directLogger.SetSink(sink)
+	directLogger.SetRateLimit(1, time.Hour)
+	defer func() {
+		directLogger.SetRateLimit(0, 0)
+		directLogger.SetSink(nil)
+	}()
+
+	log := slog.New(NewSlogHandler())
+	for i := 0; i < 3; i++ {
+		log.ErrorContext(context.Background(), "connection failed", "attempt", i)
+	}
+
+	if lines := recordedLines(sink); len(lines) != 1 {
+		t.Errorf("Expected the rate limit to apply to slog records, got %d lines", len(lines))
+	}
+}
+
+func TestSlogHandler_FallbackToCustomLogger(t *testing.T) {
+	existing := &formatOnlyLogger{}
+	previous := D
+	D = existing
+	defer func() { D = previous }()
+
+	slog.New(NewSlogHandler()).WarnContext(context.Background(), "disk almost full", "free_mb", 12)
+
+	if len(existing.messages) != 1 || existing.messages[0] != "disk almost full free_mb=12" {
+		t.Errorf("Expected the record to reach the custom logger, got %v", existing.messages)
+	}
+}
+
+func TestSlogHandler_SourceInfo(t *testing.T) {
+	Init(WithSourceInfo(true))
+	defer Init()
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	slog.New(NewSlogHandler()).InfoContext(ctx, "with source")
+	contextLogger.Flush()
+
+	if !strings.Contains(buf.String(), `"filename":"slog_handler_test.go"`) {
+		t.Errorf("Expected caller source info, got %s", buf.String())
+	}
+}
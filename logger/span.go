@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+const (
+	// spanContextKey is the key used to store the active span in context
+	spanContextKey contextKey = "span"
+)
+
+// Span represents a timed sub-operation inside a ContextLogger, such as a database
+// query or an external call. Spans nest, and each span records its own start time,
+// end time, fields and the highest severity of the lines logged while it was active.
+type Span struct {
+	id        string
+	name      string
+	parent    *Span
+	logger    *ContextLogger
+	startTime time.Time
+	endTime   time.Time
+	ended     bool
+	severity  LogLevel
+	fields    map[string]interface{}
+}
+
+// StartSpan starts a new span on the logger from context and returns a context carrying
+// the span together with a function that ends it. Lines logged through the returned
+// context reference the span, and spans started from it become its children.
+//
+// Usage:
+//
+//	ctx, end := logger.StartSpan(ctx, "db.query")
+//	defer end()
+func StartSpan(ctx context.Context, name string) (context.Context, func()) {
+	logger := FromContext(ctx)
+	span := logger.startSpan(name, logger.activeSpan(ctx))
+	return context.WithValue(ctx, spanContextKey, span), span.End
+}
+
+// SpanFromContext returns the active span in the context, or nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	if span, ok := ctx.Value(spanContextKey).(*Span); ok {
+		return span
+	}
+	return nil
+}
+
+// AddSpanValue adds a field to the active span in the context
+// It does nothing when no span is active
+func AddSpanValue(ctx context.Context, key string, value interface{}) {
+	if span := SpanFromContext(ctx); span != nil {
+		span.AddValue(key, value)
+	}
+}
+
+// ID returns the identifier of the span
+func (s *Span) ID() string {
+	return s.id
+}
+
+// Name returns the name of the span
+func (s *Span) Name() string {
+	return s.name
+}
+
+// AddValue adds a field to the span
+func (s *Span) AddValue(key string, value interface{}) {
+	s.logger.mutex.Lock()
+	defer s.logger.mutex.Unlock()
+	s.fields[key] = value
+}
+
+// AddValues adds multiple fields to the span
+func (s *Span) AddValues(fields map[string]interface{}) {
+	s.logger.mutex.Lock()
+	defer s.logger.mutex.Unlock()
+	for k, v := range fields {
+		s.fields[k] = v
+	}
+}
+
+// End records the end time of the span
+// Calling End more than once has no effect
+func (s *Span) End() {
+	s.logger.mutex.Lock()
+	defer s.logger.mutex.Unlock()
+	if s.ended {
+		return
+	}
+	s.endTime = time.Now()
+	s.ended = true
+}
+
+// recordLevel raises the severity of the span and its ancestors
+// This method assumes the logger mutex is already held by the caller
+func (s *Span) recordLevel(level LogLevel) {
+	for span := s; span != nil; span = span.parent {
+		span.severity = GetHigherLevel(span.severity, level)
+	}
+}
+
+// startSpan creates a new span under the given parent and registers it with the logger
+func (l *ContextLogger) startSpan(name string, parent *Span) *Span {
+	span := &Span{
+		id:        newSpanID(),
+		name:      name,
+		parent:    parent,
+		logger:    l,
+		startTime: time.Now(),
+		severity:  DebugLevel,
+		fields:    make(map[string]interface{}),
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.spans = append(l.spans, span)
+	return span
+}
+
+// activeSpan returns the span in the context if it belongs to this logger
+func (l *ContextLogger) activeSpan(ctx context.Context) *Span {
+	if span := SpanFromContext(ctx); span != nil && span.logger == l {
+		return span
+	}
+	return nil
+}
+
+// spanTree converts the registered spans into a tree for formatting. Spans that are still
+// open are reported with elapsed time up to now. The spans are kept until pruneSpans is
+// called, so that a document that could not be written can be flushed again.
+// This method assumes the mutex is already held by the caller
+func (l *ContextLogger) spanTree(now time.Time) []*formatter.SpanInfo {
+	if len(l.spans) == 0 {
+		return nil
+	}
+
+	infos := make(map[*Span]*formatter.SpanInfo, len(l.spans))
+	var roots []*formatter.SpanInfo
+
+	for _, span := range l.spans {
+		info := &formatter.SpanInfo{
+			ID:        span.id,
+			Name:      span.name,
+			StartTime: span.startTime.Format(time.RFC3339Nano),
+			Severity:  span.severity.String(),
+		}
+		if span.parent != nil {
+			info.ParentID = span.parent.id
+		}
+		if len(span.fields) > 0 {
+			info.Fields = make(map[string]interface{}, len(span.fields))
+			for k, v := range span.fields {
+				info.Fields[k] = v
+			}
+		}
+		if span.ended {
+			info.EndTime = span.endTime.Format(time.RFC3339Nano)
+			info.Elapsed = span.endTime.Sub(span.startTime).Milliseconds()
+		} else {
+			info.Elapsed = now.Sub(span.startTime).Milliseconds()
+		}
+		infos[span] = info
+
+		// Spans are registered after their parent, so the parent info already exists
+		// unless the parent ended and was flushed earlier
+		if parentInfo, ok := infos[span.parent]; ok && span.parent != nil {
+			parentInfo.Children = append(parentInfo.Children, info)
+		} else {
+			roots = append(roots, info)
+		}
+	}
+
+	return roots
+}
+
+// pruneSpans forgets the spans that have ended after their document was written, and resets
+// the severity of the open ones so that each document reports the lines it contains
+// This method assumes the mutex is already held by the caller
+func (l *ContextLogger) pruneSpans() {
+	remaining := l.spans[:0]
+	for _, span := range l.spans {
+		if !span.ended {
+			span.severity = DebugLevel
+			remaining = append(remaining, span)
+		}
+	}
+
+	// Clear references to dropped spans so they can be garbage collected
+	for i := len(remaining); i < len(l.spans); i++ {
+		l.spans[i] = nil
+	}
+	l.spans = remaining
+}
+
+// newSpanID generates a random 8-byte identifier encoded as 16 hex characters
+func newSpanID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		handleError("span_id", err)
+	}
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zentooo/logspan/formatter"
+)
+
+// flushAndDecode flushes the logger and decodes the JSON written to buf
+func flushAndDecode(t *testing.T, logger *ContextLogger, buf *bytes.Buffer) *formatter.LogOutput {
+	t.Helper()
+	logger.Flush()
+
+	var output formatter.LogOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse output: %v, output: %s", err, buf.String())
+	}
+	return &output
+}
+
+func TestStartSpan_NestedTree(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	Infof(ctx, "request started")
+
+	dbCtx, endDB := StartSpan(ctx, "db")
+	AddSpanValue(dbCtx, "table", "users")
+	queryCtx, endQuery := StartSpan(dbCtx, "db.query")
+	Warnf(queryCtx, "slow query")
+	endQuery()
+	Infow(dbCtx, "rows loaded", "rows", 3)
+	endDB()
+
+	output := flushAndDecode(t, contextLogger, &buf)
+
+	if len(output.Runtime.Spans) != 1 {
+		t.Fatalf("Expected 1 root span, got %d", len(output.Runtime.Spans))
+	}
+	db := output.Runtime.Spans[0]
+	if db.Name != "db" || db.EndTime == "" {
+		t.Errorf("Unexpected root span: %+v", db)
+	}
+	if db.Fields["table"] != "users" {
+		t.Errorf("Expected span field table=users, got %v", db.Fields)
+	}
+	if db.Severity != "WARN" {
+		t.Errorf("Expected root span severity to include child lines (WARN), got %s", db.Severity)
+	}
+	if len(db.Children) != 1 || db.Children[0].Name != "db.query" {
+		t.Fatalf("Expected db.query child span, got %+v", db.Children)
+	}
+	query := db.Children[0]
+	if query.ParentID != db.ID {
+		t.Errorf("Expected child parentId %s, got %s", db.ID, query.ParentID)
+	}
+
+	lines := output.Runtime.Lines
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	if lines[0].SpanID != "" {
+		t.Errorf("Expected line outside spans to have no span_id, got %s", lines[0].SpanID)
+	}
+	if lines[1].SpanID != query.ID {
+		t.Errorf("Expected line to reference db.query span, got %s", lines[1].SpanID)
+	}
+	if lines[2].SpanID != db.ID {
+		t.Errorf("Expected line to reference db span, got %s", lines[2].SpanID)
+	}
+}
+
+func TestStartSpan_OpenSpanKeptAcrossFlush(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	_, endOpen := StartSpan(ctx, "stream")
+	_, endDone := StartSpan(ctx, "auth")
+	endDone()
+
+	output := flushAndDecode(t, contextLogger, &buf)
+	if len(output.Runtime.Spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(output.Runtime.Spans))
+	}
+	if output.Runtime.Spans[0].EndTime != "" {
+		t.Error("Expected open span to have no end time")
+	}
+
+	time.Sleep(time.Millisecond)
+	endOpen()
+	buf.Reset()
+
+	output = flushAndDecode(t, contextLogger, &buf)
+	if len(output.Runtime.Spans) != 1 || output.Runtime.Spans[0].Name != "stream" {
+		t.Fatalf("Expected only the previously open span, got %+v", output.Runtime.Spans)
+	}
+	if output.Runtime.Spans[0].EndTime == "" {
+		t.Error("Expected span to be ended")
+	}
+
+	buf.Reset()
+	output = flushAndDecode(t, contextLogger, &buf)
+	if len(output.Runtime.Spans) != 0 {
+		t.Errorf("Expected ended spans to be forgotten after flush, got %+v", output.Runtime.Spans)
+	}
+}
+
+func TestStartSpan_EndIsIdempotent(t *testing.T) {
+	contextLogger := NewContextLogger()
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	spanCtx, end := StartSpan(ctx, "once")
+	end()
+	span := SpanFromContext(spanCtx)
+	firstEnd := span.endTime
+	end()
+
+	if !span.endTime.Equal(firstEnd) {
+		t.Error("Expected second End call to keep the original end time")
+	}
+	if span.Name() != "once" || len(span.ID()) != 16 {
+		t.Errorf("Unexpected span identity: name=%s id=%s", span.Name(), span.ID())
+	}
+}
+
+func TestSpanFromContext_NoSpan(t *testing.T) {
+	if span := SpanFromContext(context.Background()); span != nil {
+		t.Errorf("Expected nil span, got %+v", span)
+	}
+
+	// Adding a value without an active span must not panic
+	AddSpanValue(context.Background(), "key", "value")
+}
+
+func TestStartSpan_SpanFromOtherLoggerIgnored(t *testing.T) {
+	var buf bytes.Buffer
+	first := NewContextLogger()
+	second := NewContextLogger()
+	second.SetOutput(&buf)
+
+	spanCtx, end := StartSpan(WithLogger(context.Background(), first), "first.span")
+	defer end()
+
+	ctx := WithLogger(spanCtx, second)
+	Infof(ctx, "logged on second logger")
+
+	output := flushAndDecode(t, second, &buf)
+	if output.Runtime.Lines[0].SpanID != "" {
+		t.Error("Expected span of another logger not to be referenced")
+	}
+}
+
+// failOnceFormatter fails the first Format call and formats as JSON afterwards
+type failOnceFormatter struct {
+	failed bool
+}
+
+func (f *failOnceFormatter) Format(output *formatter.LogOutput) ([]byte, error) {
+	if !f.failed {
+		f.failed = true
+		return nil, errors.New("format failed")
+	}
+	return formatter.NewJSONFormatter().Format(output)
+}
+
+func TestStartSpan_EndedSpanKeptUntilWritten(t *testing.T) {
+	previous := GetGlobalErrorHandler()
+	SetGlobalErrorHandler(ErrorHandlerFunc(func(operation string, err error) {}))
+	defer SetGlobalErrorHandler(previous)
+
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	contextLogger.SetFormatter(&failOnceFormatter{})
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	spanCtx, end := StartSpan(ctx, "db.query")
+	Infof(spanCtx, "query executed")
+	end()
+
+	// The first flush fails and keeps the entries for the next one
+	contextLogger.Flush()
+	if len(contextLogger.entries) != 1 {
+		t.Fatalf("Expected the entries to be kept after a failed flush, got %d", len(contextLogger.entries))
+	}
+	buf.Reset()
+
+	output := flushAndDecode(t, contextLogger, &buf)
+	if len(output.Runtime.Lines) != 1 || len(output.Runtime.Spans) != 1 {
+		t.Fatalf("Expected the line and its span, got %+v", output.Runtime)
+	}
+	if output.Runtime.Lines[0].SpanID != output.Runtime.Spans[0].ID {
+		t.Errorf("Expected the line to reference the span, got %q and %q", output.Runtime.Lines[0].SpanID, output.Runtime.Spans[0].ID)
+	}
+	if !strings.Contains(buf.String(), `"spanId":"`+output.Runtime.Spans[0].ID+`"`) {
+		t.Errorf("Expected the line key spanId, got %s", buf.String())
+	}
+}
+
+func TestStartSpan_OpenSpanSeverityResetEachFlush(t *testing.T) {
+	var buf bytes.Buffer
+	contextLogger := NewContextLogger()
+	contextLogger.SetOutput(&buf)
+	ctx := WithLogger(context.Background(), contextLogger)
+
+	spanCtx, end := StartSpan(ctx, "stream")
+	defer end()
+
+	Errorf(spanCtx, "chunk failed")
+	output := flushAndDecode(t, contextLogger, &buf)
+	if output.Runtime.Spans[0].Severity != "ERROR" {
+		t.Fatalf("Expected severity ERROR, got %s", output.Runtime.Spans[0].Severity)
+	}
+
+	buf.Reset()
+	Infof(spanCtx, "chunk sent")
+	output = flushAndDecode(t, contextLogger, &buf)
+	if output.Runtime.Spans[0].Severity != "INFO" {
+		t.Errorf("Expected the next document to report INFO, got %s", output.Runtime.Spans[0].Severity)
+	}
+}
@@ -0,0 +1,194 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// traceContextKey is the key used to store the W3C trace context in context
+	traceContextKey contextKey = "trace"
+)
+
+// Trace flag bits defined by the W3C Trace Context specification
+const (
+	// TraceFlagSampled indicates that the caller may have recorded trace data
+	TraceFlagSampled byte = 0x01
+)
+
+// traceparentLength is the length of a version 00 traceparent header value
+const traceparentLength = 55
+
+// Errors returned by ParseTraceparent
+var (
+	ErrInvalidTraceparent = errors.New("invalid traceparent header")
+)
+
+// TraceContext holds the W3C Trace Context of the current operation
+// See https://www.w3.org/TR/trace-context/
+type TraceContext struct {
+	// TraceID is the 32 hex character identifier of the whole trace
+	TraceID string
+
+	// SpanID is the 16 hex character identifier of the current operation
+	SpanID string
+
+	// ParentSpanID is the span ID received from the caller, empty for a new trace
+	ParentSpanID string
+
+	// Flags holds the trace flags, such as TraceFlagSampled
+	Flags byte
+
+	// TraceState holds the vendor-specific tracestate header value, passed through unchanged
+	TraceState string
+}
+
+// NewTraceContext creates a trace context for a new, sampled trace
+func NewTraceContext() TraceContext {
+	return TraceContext{
+		TraceID: newTraceID(),
+		SpanID:  newSpanID(),
+		Flags:   TraceFlagSampled,
+	}
+}
+
+// ParseTraceparent parses a traceparent header value into the caller's trace context
+// The parent ID of the header becomes SpanID; use Child to derive the context of the
+// current operation.
+func ParseTraceparent(header string) (TraceContext, error) {
+	header = strings.TrimSpace(header)
+	if !isValidTraceparentLayout(header) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	traceID := header[3:35]
+	parentID := header[36:52]
+	flags := header[53:55]
+	if !isValidTraceID(traceID) || !isValidTraceID(parentID) || !isLowerHex(flags) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	flagBytes, err := hex.DecodeString(flags)
+	if err != nil {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  parentID,
+		Flags:   flagBytes[0],
+	}, nil
+}
+
+// isValidTraceparentLayout checks the version, the length and the dashes of a traceparent
+// header value
+func isValidTraceparentLayout(header string) bool {
+	if len(header) < traceparentLength {
+		return false
+	}
+
+	version := header[0:2]
+	if !isLowerHex(version) || version == "ff" {
+		return false
+	}
+	// Version 00 has an exact length; future versions may append fields after a dash
+	if version == "00" && len(header) != traceparentLength {
+		return false
+	}
+	if len(header) > traceparentLength && header[traceparentLength] != '-' {
+		return false
+	}
+	return header[2] == '-' && header[35] == '-' && header[52] == '-'
+}
+
+// isValidTraceID reports whether id is a lowercase hex trace or span ID that is not all zero
+func isValidTraceID(id string) bool {
+	return isLowerHex(id) && !isAllZero(id)
+}
+
+// Child returns a trace context for a new operation in the same trace,
+// with the receiver's span as parent
+func (tc TraceContext) Child() TraceContext {
+	return TraceContext{
+		TraceID:      tc.TraceID,
+		SpanID:       newSpanID(),
+		ParentSpanID: tc.SpanID,
+		Flags:        tc.Flags,
+		TraceState:   tc.TraceState,
+	}
+}
+
+// Sampled reports whether the sampled flag is set
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&TraceFlagSampled != 0
+}
+
+// IsValid reports whether the trace context has valid trace and span IDs
+func (tc TraceContext) IsValid() bool {
+	return len(tc.TraceID) == 32 && isValidTraceID(tc.TraceID) &&
+		len(tc.SpanID) == 16 && isValidTraceID(tc.SpanID)
+}
+
+// FlagsString returns the trace flags as two hex characters
+func (tc TraceContext) FlagsString() string {
+	return fmt.Sprintf("%02x", tc.Flags)
+}
+
+// Traceparent formats the trace context as a version 00 traceparent header value
+// with SpanID as the parent ID
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.FlagsString()
+}
+
+// WithTraceContext returns a new context with the trace context attached
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey, tc)
+}
+
+// TraceContextFromContext retrieves the trace context from the context
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey).(TraceContext)
+	return tc, ok
+}
+
+// TraceIDFromContext returns the trace ID from the context, or an empty string
+func TraceIDFromContext(ctx context.Context) string {
+	tc, _ := TraceContextFromContext(ctx)
+	return tc.TraceID
+}
+
+// SpanIDFromContext returns the trace span ID of the current operation from the context,
+// or an empty string
+func SpanIDFromContext(ctx context.Context) string {
+	tc, _ := TraceContextFromContext(ctx)
+	return tc.SpanID
+}
+
+// newTraceID generates a random 16-byte identifier encoded as 32 hex characters
+func newTraceID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		handleError("trace_id", err)
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// isLowerHex reports whether s consists only of lowercase hex characters
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isAllZero reports whether s consists only of '0' characters
+func isAllZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{"valid sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"valid not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"empty", "", true},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"version 00 too long", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", true},
+		{"zero parent id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true},
+		{"bad separator", "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, err := ParseTraceparent(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceparent(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			}
+			if !tt.wantErr && (tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.SpanID != "00f067aa0ba902b7") {
+				t.Errorf("Unexpected trace context: %+v", tc)
+			}
+		})
+	}
+}
+
+func TestTraceContext_Child(t *testing.T) {
+	parent, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("ParseTraceparent failed: %v", err)
+	}
+	parent.TraceState = "vendor=a"
+
+	child := parent.Child()
+	if child.TraceID != parent.TraceID || child.ParentSpanID != parent.SpanID {
+		t.Errorf("Expected child to continue the trace, got %+v", child)
+	}
+	if child.SpanID == parent.SpanID || !child.IsValid() {
+		t.Errorf("Expected a new valid span ID, got %s", child.SpanID)
+	}
+	if !child.Sampled() || child.TraceState != "vendor=a" {
+		t.Errorf("Expected flags and tracestate to be inherited, got %+v", child)
+	}
+}
+
+func TestNewTraceContext(t *testing.T) {
+	tc := NewTraceContext()
+	if !tc.IsValid() || !tc.Sampled() || tc.ParentSpanID != "" {
+		t.Errorf("Unexpected new trace context: %+v", tc)
+	}
+
+	roundTrip, err := ParseTraceparent(tc.Traceparent())
+	if err != nil {
+		t.Fatalf("Traceparent() produced an unparsable header: %v", err)
+	}
+	if roundTrip.TraceID != tc.TraceID || roundTrip.SpanID != tc.SpanID || roundTrip.Flags != tc.Flags {
+		t.Errorf("Round trip mismatch: %+v vs %+v", roundTrip, tc)
+	}
+}
+
+func TestTraceContextFromContext(t *testing.T) {
+	ctx := context.Background()
+	if TraceIDFromContext(ctx) != "" || SpanIDFromContext(ctx) != "" {
+		t.Error("Expected empty IDs without a trace context")
+	}
+
+	tc := NewTraceContext()
+	ctx = WithTraceContext(ctx, tc)
+	if TraceIDFromContext(ctx) != tc.TraceID || SpanIDFromContext(ctx) != tc.SpanID {
+		t.Error("Expected IDs from the stored trace context")
+	}
+}
@@ -0,0 +1,341 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zentooo/logspan/logger"
+)
+
+// OverflowPolicy decides what an AsyncWriter does with a write when its queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the queue has room, so no document is lost
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the document being written
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest queued document to make room
+	OverflowDropOldest
+
+	// OverflowDropBelowLevel discards the document being written if its severity is below
+	// the level set with WithDropBelowLevel, and waits for room otherwise
+	OverflowDropBelowLevel
+)
+
+// Default settings of AsyncWriter
+const (
+	DefaultQueueSize          = 1024
+	DefaultDropReportInterval = 10 * time.Second
+)
+
+// asyncRecord is a queued document, or a sync request when done is set
+type asyncRecord struct {
+	data []byte
+	done chan error
+}
+
+// AsyncWriter is an io.Writer that hands documents to a background goroutine, so that
+// loggers never wait for slow output while holding their lock
+//
+// Documents are queued in a bounded queue and written to the underlying writer in order.
+// What happens when the queue is full is decided by the OverflowPolicy. Dropped documents
+// are counted, and the count is reported through the global error handler of the logger
+// package at most once per report interval, and on Sync and Close.
+//
+// AsyncWriter implements logger.LevelWriter, so loggers pass the severity of each document.
+// Documents written with plain Write are treated as INFO.
+type AsyncWriter struct {
+	out            io.Writer
+	queue          chan asyncRecord
+	policy         OverflowPolicy
+	dropBelow      logger.LogLevel
+	reportInterval time.Duration
+
+	closeMutex sync.RWMutex  // Held for reading while enqueueing, for writing while closing the queue
+	closeOnce  sync.Once     // Starts closing only once
+	closing    chan struct{} // Closed by Close, releases the writers waiting for room
+	closed     bool
+	done       chan struct{}
+
+	dropped    atomic.Uint64 // Total number of dropped documents
+	unreported atomic.Uint64 // Dropped documents not yet reported
+	lastReport time.Time     // Only accessed by the background goroutine
+}
+
+// AsyncOption is a function that configures an AsyncWriter
+type AsyncOption func(*AsyncWriter)
+
+// WithQueueSize sets the number of documents the queue holds (default DefaultQueueSize)
+func WithQueueSize(size int) AsyncOption {
+	return func(w *AsyncWriter) {
+		if size > 0 {
+			w.queue = make(chan asyncRecord, size)
+		}
+	}
+}
+
+// WithOverflowPolicy sets what happens to writes when the queue is full (default OverflowBlock)
+func WithOverflowPolicy(policy OverflowPolicy) AsyncOption {
+	return func(w *AsyncWriter) {
+		w.policy = policy
+	}
+}
+
+// WithDropBelowLevel drops documents below the level when the queue is full and waits
+// for room for the others. It sets the policy to OverflowDropBelowLevel.
+func WithDropBelowLevel(level logger.LogLevel) AsyncOption {
+	return func(w *AsyncWriter) {
+		w.policy = OverflowDropBelowLevel
+		w.dropBelow = level
+	}
+}
+
+// WithDropReportInterval sets the minimum interval between two drop reports
+// (default DefaultDropReportInterval)
+func WithDropReportInterval(interval time.Duration) AsyncOption {
+	return func(w *AsyncWriter) {
+		w.reportInterval = interval
+	}
+}
+
+// NewAsyncWriter creates an AsyncWriter that writes to out and starts its background goroutine
+// Call Close on shutdown to write the queued documents.
+//
+// Usage:
+//
+//	async := sink.NewAsyncWriter(os.Stdout,
+//	    sink.WithQueueSize(4096),
+//	    sink.WithDropBelowLevel(logger.WarnLevel),
+//	)
+//	defer async.Close(context.Background())
+//	logger.Init(logger.WithOutput(async))
+func NewAsyncWriter(out io.Writer, options ...AsyncOption) *AsyncWriter {
+	w := &AsyncWriter{
+		out:            out,
+		queue:          make(chan asyncRecord, DefaultQueueSize),
+		policy:         OverflowBlock,
+		reportInterval: DefaultDropReportInterval,
+		closing:        make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	for _, option := range options {
+		option(w)
+	}
+
+	go w.run()
+	return w
+}
+
+// Write queues p as an INFO document
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(logger.InfoLevel, p)
+}
+
+// WriteLevel queues p with its severity and returns without waiting for the output
+// Dropped documents are not reported as errors; see Dropped.
+func (w *AsyncWriter) WriteLevel(level logger.LogLevel, p []byte) (int, error) {
+	// The caller may reuse p after returning
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	if w.isClosing() {
+		return 0, ErrClosed
+	}
+	w.closeMutex.RLock()
+	defer w.closeMutex.RUnlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	record := asyncRecord{data: data}
+	select {
+	case w.queue <- record:
+		return len(p), nil
+	default:
+	}
+
+	// The queue is full
+	if !w.overflow(level, record) {
+		return 0, ErrClosed
+	}
+	return len(p), nil
+}
+
+// overflow handles a record that found the queue full according to the overflow policy
+// It returns false if Close was called while waiting for room.
+func (w *AsyncWriter) overflow(level logger.LogLevel, record asyncRecord) bool {
+	switch w.policy {
+	case OverflowDropNewest:
+		w.drop()
+		return true
+	case OverflowDropOldest:
+		return w.dropOldest(record)
+	case OverflowDropBelowLevel:
+		if level < w.dropBelow {
+			w.drop()
+			return true
+		}
+		return w.enqueue(record)
+	default:
+		return w.enqueue(record)
+	}
+}
+
+// dropOldest discards the oldest queued documents until the record fits
+// It returns false if Close was called while waiting for room.
+func (w *AsyncWriter) dropOldest(record asyncRecord) bool {
+	for {
+		select {
+		case w.queue <- record:
+			return true
+		default:
+		}
+		select {
+		case oldest := <-w.queue:
+			if oldest.done != nil {
+				// Never discard a sync request; queue it again behind this document
+				return w.enqueue(record) && w.enqueue(oldest)
+			}
+			w.drop()
+		default:
+		}
+	}
+}
+
+// Sync waits until all documents queued before the call have been written, then syncs the
+// underlying writer if it has a Sync method
+func (w *AsyncWriter) Sync() error {
+	done := make(chan error, 1)
+
+	if w.isClosing() {
+		return ErrClosed
+	}
+	w.closeMutex.RLock()
+	if w.closed {
+		w.closeMutex.RUnlock()
+		return ErrClosed
+	}
+	queued := w.enqueue(asyncRecord{done: done})
+	w.closeMutex.RUnlock()
+	if !queued {
+		return ErrClosed
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-w.done:
+		select {
+		case err := <-done:
+			return err
+		default:
+			// The request was lost by a writer that gave up when Close was called
+			return ErrClosed
+		}
+	}
+}
+
+// Close stops accepting documents and waits until the queued ones have been written or
+// ctx is done. Writers waiting for room in the queue give up with ErrClosed. The underlying
+// writer is not closed. Calling Close more than once is safe.
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		close(w.closing)
+		// The lock is released once the waiting writers give up, which is not bounded by
+		// ctx if the output is stuck, so it is taken in the background
+		go w.closeQueue()
+	})
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeQueue closes the queue once no writer is enqueueing, which stops the background
+// goroutine after the queued documents have been written
+func (w *AsyncWriter) closeQueue() {
+	w.closeMutex.Lock()
+	defer w.closeMutex.Unlock()
+	w.closed = true
+	close(w.queue)
+}
+
+// isClosing reports whether Close has been called
+func (w *AsyncWriter) isClosing() bool {
+	select {
+	case <-w.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// enqueue waits until the queue has room for the record and returns false if Close is
+// called first. The caller must hold closeMutex for reading.
+func (w *AsyncWriter) enqueue(record asyncRecord) bool {
+	select {
+	case w.queue <- record:
+		return true
+	case <-w.closing:
+		return false
+	}
+}
+
+// Dropped returns the total number of documents dropped because the queue was full
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// drop counts a dropped document
+func (w *AsyncWriter) drop() {
+	w.dropped.Add(1)
+	w.unreported.Add(1)
+}
+
+// run writes queued documents until the queue is closed and drained
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	for record := range w.queue {
+		if record.done != nil {
+			w.reportDrops()
+			record.done <- w.syncOutput()
+			continue
+		}
+
+		if _, err := w.out.Write(record.data); err != nil {
+			reportError("async_write", err)
+		}
+		if time.Since(w.lastReport) >= w.reportInterval {
+			w.reportDrops()
+		}
+	}
+
+	w.reportDrops()
+}
+
+// reportDrops reports documents dropped since the last report to the error handler
+func (w *AsyncWriter) reportDrops() {
+	if n := w.unreported.Swap(0); n > 0 {
+		reportError("async_drop", fmt.Errorf("dropped %d log documents because the queue was full", n))
+		w.lastReport = time.Now()
+	}
+}
+
+// syncOutput syncs the underlying writer if it supports it
+func (w *AsyncWriter) syncOutput() error {
+	if syncer, ok := w.out.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zentooo/logspan/logger"
+)
+
+// gatedWriter blocks every write until the gate is opened
+type gatedWriter struct {
+	gate   chan struct{}
+	mutex  sync.Mutex
+	buf    bytes.Buffer
+	synced int
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{gate: make(chan struct{})}
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.gate
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.buf.Write(p)
+}
+
+func (g *gatedWriter) Sync() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.synced++
+	return nil
+}
+
+func (g *gatedWriter) String() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.buf.String()
+}
+
+// captureErrors replaces the global error handler for the duration of the test
+func captureErrors(t *testing.T) func() []string {
+	t.Helper()
+	var mutex sync.Mutex
+	var messages []string
+	previous := logger.GetGlobalErrorHandler()
+	logger.SetGlobalErrorHandler(logger.ErrorHandlerFunc(func(operation string, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		messages = append(messages, operation+": "+err.Error())
+	}))
+	t.Cleanup(func() { logger.SetGlobalErrorHandler(previous) })
+
+	return func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), messages...)
+	}
+}
+
+// fillQueue writes the first document and waits until the worker is blocked writing it,
+// then fills the queue with the remaining documents
+func fillQueue(t *testing.T, w *AsyncWriter, first string, rest ...string) {
+	t.Helper()
+	w.WriteLevel(logger.InfoLevel, []byte(first))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(w.queue) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Worker did not take the first document")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, doc := range rest {
+		w.WriteLevel(logger.InfoLevel, []byte(doc))
+	}
+	if len(w.queue) != cap(w.queue) {
+		t.Fatalf("Expected a full queue, got %d/%d", len(w.queue), cap(w.queue))
+	}
+}
+
+func TestAsyncWriter_WritesInOrder(t *testing.T) {
+	out := newGatedWriter()
+	close(out.gate)
+	w := NewAsyncWriter(out)
+
+	for _, doc := range []string{"a\n", "b\n", "c\n"} {
+		w.Write([]byte(doc))
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if out.String() != "a\nb\nc\n" {
+		t.Errorf("Expected documents in order, got %q", out.String())
+	}
+	if _, err := w.Write([]byte("late\n")); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestAsyncWriter_CopiesInput(t *testing.T) {
+	out := newGatedWriter()
+	w := NewAsyncWriter(out)
+
+	buf := []byte("original\n")
+	w.Write(buf)
+	copy(buf, "modified\n")
+
+	close(out.gate)
+	w.Close(context.Background())
+	if out.String() != "original\n" {
+		t.Errorf("Expected the queued copy to be written, got %q", out.String())
+	}
+}
+
+func TestAsyncWriter_OverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []AsyncOption
+		level    logger.LogLevel
+		expected string
+		dropped  uint64
+	}{
+		{"drop newest", []AsyncOption{WithOverflowPolicy(OverflowDropNewest)}, logger.ErrorLevel, "1\n2\n3\n", 1},
+		{"drop oldest", []AsyncOption{WithOverflowPolicy(OverflowDropOldest)}, logger.ErrorLevel, "1\n3\nnew\n", 1},
+		{"drop below level drops", []AsyncOption{WithDropBelowLevel(logger.WarnLevel)}, logger.InfoLevel, "1\n2\n3\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := captureErrors(t)
+			out := newGatedWriter()
+			w := NewAsyncWriter(out, append(tt.options, WithQueueSize(2))...)
+
+			// "1" is taken by the worker, "2" and "3" fill the queue
+			fillQueue(t, w, "1\n", "2\n", "3\n")
+			w.WriteLevel(tt.level, []byte("new\n"))
+
+			close(out.gate)
+			w.Close(context.Background())
+
+			if out.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, out.String())
+			}
+			if w.Dropped() != tt.dropped {
+				t.Errorf("Expected %d dropped, got %d", tt.dropped, w.Dropped())
+			}
+			reported := strings.Join(errors(), "\n")
+			if !strings.Contains(reported, "dropped 1 log documents") {
+				t.Errorf("Expected drops to be reported, got %q", reported)
+			}
+		})
+	}
+}
+
+func TestAsyncWriter_DropBelowLevelBlocksImportantDocuments(t *testing.T) {
+	out := newGatedWriter()
+	w := NewAsyncWriter(out, WithQueueSize(2), WithDropBelowLevel(logger.WarnLevel))
+	fillQueue(t, w, "1\n", "2\n", "3\n")
+
+	written := make(chan struct{})
+	go func() {
+		w.WriteLevel(logger.ErrorLevel, []byte("error\n"))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("Expected the ERROR document to wait for room in the queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(out.gate)
+	<-written
+	w.Close(context.Background())
+	if out.String() != "1\n2\n3\nerror\n" || w.Dropped() != 0 {
+		t.Errorf("Expected no drops, got %q (dropped %d)", out.String(), w.Dropped())
+	}
+}
+
+func TestAsyncWriter_Sync(t *testing.T) {
+	out := newGatedWriter()
+	close(out.gate)
+	w := NewAsyncWriter(out)
+	defer w.Close(context.Background())
+
+	w.Write([]byte("a\n"))
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if out.String() != "a\n" || out.synced != 1 {
+		t.Errorf("Expected the document to be written and synced, got %q (synced %d)", out.String(), out.synced)
+	}
+}
+
+func TestAsyncWriter_CloseTimeout(t *testing.T) {
+	out := newGatedWriter()
+	w := NewAsyncWriter(out)
+	w.Write([]byte("stuck\n"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+
+	close(out.gate)
+	if err := w.Close(context.Background()); err != nil {
+		t.Errorf("Expected the second Close to finish draining, got %v", err)
+	}
+	if out.String() != "stuck\n" {
+		t.Errorf("Expected the document to be written eventually, got %q", out.String())
+	}
+}
+
+func TestAsyncWriter_CloseTimeoutWithBlockedWriter(t *testing.T) {
+	out := newGatedWriter()
+	w := NewAsyncWriter(out, WithQueueSize(1))
+	fillQueue(t, w, "1\n", "2\n")
+
+	// The writer waits for room in the full queue while the output is stuck
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := w.WriteLevel(logger.InfoLevel, []byte("3\n"))
+		blocked <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() { closed <- w.Close(ctx) }()
+
+	select {
+	case err := <-closed:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Close to return after the deadline while the output is stuck")
+	}
+
+	select {
+	case err := <-blocked:
+		if err != ErrClosed {
+			t.Errorf("Expected the waiting writer to get ErrClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiting writer to be released by Close")
+	}
+
+	late := make(chan error, 1)
+	go func() {
+		_, err := w.Write([]byte("late\n"))
+		late <- err
+	}()
+	select {
+	case err := <-late:
+		if err != ErrClosed {
+			t.Errorf("Expected ErrClosed after Close, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected writes after Close not to block")
+	}
+
+	close(out.gate)
+	if err := w.Close(context.Background()); err != nil {
+		t.Errorf("Expected the second Close to finish draining, got %v", err)
+	}
+	if out.String() != "1\n2\n" {
+		t.Errorf("Expected the queued documents to be written, got %q", out.String())
+	}
+}
+
+func TestAsyncWriter_ReceivesLevelsFromLoggers(t *testing.T) {
+	out := newGatedWriter()
+	w := NewAsyncWriter(out, WithQueueSize(1), WithDropBelowLevel(logger.ErrorLevel))
+	fillQueue(t, w, "1\n", "2\n")
+
+	contextLogger := logger.NewContextLogger()
+	contextLogger.SetOutput(w)
+
+	// INFO aggregate is dropped while the queue is full
+	contextLogger.Infof("dropped")
+	contextLogger.Flush()
+	if w.Dropped() != 1 {
+		t.Errorf("Expected the INFO document to be dropped, got %d drops", w.Dropped())
+	}
+
+	close(out.gate)
+	contextLogger.Errorf("kept")
+	contextLogger.Flush()
+	w.Close(context.Background())
+
+	if !strings.Contains(out.String(), "kept") || strings.Contains(out.String(), `"dropped"`) {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
@@ -0,0 +1,50 @@
+// Package sink provides output destinations for the logger package.
+//
+// The writers in this package implement io.Writer and can be passed to
+// logger.WithOutput or to SetOutput of individual loggers.
+//
+// # Rotating Files
+//
+// RotatingFile writes to a file and rotates it by size and by time:
+//
+//	file, err := sink.NewRotatingFile("/var/log/app/app.log",
+//	    sink.WithMaxSize(100<<20),              // Rotate before exceeding 100 MiB
+//	    sink.WithRotationInterval(24*time.Hour), // Rotate daily (midnight UTC)
+//	    sink.WithMaxBackups(7),                  // Keep the 7 newest backups
+//	    sink.WithCompression(true),              // Gzip rotated files
+//	    sink.WithReopenOnSignal(),               // Reopen on SIGHUP (logrotate)
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer file.Close()
+//
+//	logger.Init(logger.WithOutput(file))
+//
+// Rotation only happens between writes. Loggers write each flushed document with a
+// single Write call, so an aggregated request log is never split across two files.
+//
+// # Asynchronous Output
+//
+// AsyncWriter hands documents to a background goroutine through a bounded queue, so
+// that flushing a logger never waits for slow I/O:
+//
+//	async := sink.NewAsyncWriter(file,
+//	    sink.WithQueueSize(4096),
+//	    sink.WithOverflowPolicy(sink.OverflowDropOldest),
+//	)
+//	defer async.Close(context.Background())
+//
+//	logger.Init(logger.WithOutput(async))
+//
+// When the queue is full, the OverflowPolicy decides whether the write waits or a
+// document is dropped. AsyncWriter implements logger.LevelWriter, so with
+// WithDropBelowLevel only documents below a severity are dropped.
+//
+// # Error Handling
+//
+// Errors of Write are returned to the logger, which passes them to its error handler.
+// Errors of background work, such as compression, are reported to the global error
+// handler of the logger package (see logger.SetGlobalErrorHandler), as are the
+// counts of documents dropped by AsyncWriter.
+package sink
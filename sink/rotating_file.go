@@ -0,0 +1,484 @@
+package sink
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zentooo/logspan/logger"
+)
+
+// backupTimeFormat is the timestamp format used in backup file names
+// It sorts lexicographically in time order and contains no characters that are invalid in file names
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// compressSuffix is appended to the name of compressed backups
+const compressSuffix = ".gz"
+
+// ErrClosed is returned when writing to a closed writer
+var ErrClosed = errors.New("sink: writer is closed")
+
+// RotatingFile is an io.Writer that writes to a file and rotates it by size and time
+//
+// Each call to Write is written to a single file: rotation only happens between writes,
+// so a log document flushed by a logger is never split across two files. A document
+// larger than the maximum size is written to a file of its own.
+//
+// Rotated files are renamed to "<name>-<timestamp><ext>" in the same directory, for
+// example "app-2024-01-02T15-04-05.000.log", and optionally compressed with gzip.
+//
+// RotatingFile is safe for concurrent use.
+type RotatingFile struct {
+	filename   string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	compress   bool
+	fileMode   os.FileMode
+	signals    []os.Signal
+	now        func() time.Time
+
+	mutex        sync.Mutex
+	file         *os.File
+	size         int64
+	nextRotation time.Time
+	closed       bool
+
+	signalCh  chan os.Signal
+	done      chan struct{}
+	millMutex sync.Mutex     // Serializes compression and cleanup of backups
+	millGroup sync.WaitGroup // Tracks pending compression and cleanup
+}
+
+// RotatingFileOption is a function that configures a RotatingFile
+type RotatingFileOption func(*RotatingFile)
+
+// WithMaxSize rotates the file before a write that would make it larger than maxBytes
+// Zero or a negative value disables size-based rotation
+func WithMaxSize(maxBytes int64) RotatingFileOption {
+	return func(r *RotatingFile) {
+		r.maxSize = maxBytes
+	}
+}
+
+// WithRotationInterval rotates the file on the first write after each interval boundary
+// Boundaries are multiples of the interval since the zero time, so time.Hour rotates at
+// the top of every hour and 24*time.Hour at midnight UTC. Zero disables time-based rotation.
+func WithRotationInterval(interval time.Duration) RotatingFileOption {
+	return func(r *RotatingFile) {
+		r.interval = interval
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files and removes the oldest ones
+// Zero keeps all backups
+func WithMaxBackups(n int) RotatingFileOption {
+	return func(r *RotatingFile) {
+		r.maxBackups = n
+	}
+}
+
+// WithCompression enables or disables gzip compression of rotated files
+// Compression runs in the background and does not block writes
+func WithCompression(enabled bool) RotatingFileOption {
+	return func(r *RotatingFile) {
+		r.compress = enabled
+	}
+}
+
+// WithFileMode sets the permission bits used when creating log files (default 0644)
+func WithFileMode(mode os.FileMode) RotatingFileOption {
+	return func(r *RotatingFile) {
+		r.fileMode = mode
+	}
+}
+
+// WithReopenOnSignal reopens the file when one of the signals is received, which lets
+// external tools such as logrotate move the file away. Without signals, SIGHUP is used.
+func WithReopenOnSignal(signals ...os.Signal) RotatingFileOption {
+	return func(r *RotatingFile) {
+		if len(signals) == 0 {
+			signals = defaultReopenSignals()
+		}
+		r.signals = append(r.signals, signals...)
+	}
+}
+
+// NewRotatingFile opens (or creates) the file for appending and returns a rotating writer
+// The directory of the file is created if it does not exist.
+//
+// Usage:
+//
+//	file, err := sink.NewRotatingFile("/var/log/app/app.log",
+//	    sink.WithMaxSize(100<<20),
+//	    sink.WithRotationInterval(24*time.Hour),
+//	    sink.WithMaxBackups(7),
+//	    sink.WithCompression(true),
+//	    sink.WithReopenOnSignal(syscall.SIGHUP),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer file.Close()
+//	logger.Init(logger.WithOutput(file))
+func NewRotatingFile(filename string, options ...RotatingFileOption) (*RotatingFile, error) {
+	r := &RotatingFile{
+		filename: filename,
+		fileMode: 0644,
+		now:      time.Now,
+		done:     make(chan struct{}),
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	if err := r.openFile(); err != nil {
+		return nil, err
+	}
+
+	if len(r.signals) > 0 {
+		r.signalCh = make(chan os.Signal, 1)
+		signal.Notify(r.signalCh, r.signals...)
+		go r.watchSignals()
+	}
+	return r, nil
+}
+
+// Write writes p to the current file, rotating it first if needed
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return 0, ErrClosed
+	}
+	if r.file == nil {
+		// A previous reopen failed; try again
+		if err := r.openFile(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it to a backup and opens a new file
+func (r *RotatingFile) Rotate() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+	return r.rotate()
+}
+
+// Reopen closes and reopens the file without renaming it
+// Use it after the file has been moved by an external tool.
+func (r *RotatingFile) Reopen() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+	return r.openFile()
+}
+
+// Sync commits the contents of the current file to stable storage
+func (r *RotatingFile) Sync() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+// Close stops signal handling, waits for pending compression and closes the file
+func (r *RotatingFile) Close() error {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.closed = true
+	if r.signalCh != nil {
+		signal.Stop(r.signalCh)
+	}
+	close(r.done)
+	err := r.closeFile()
+	r.mutex.Unlock()
+
+	r.millGroup.Wait()
+	return err
+}
+
+// watchSignals reopens the file whenever a configured signal is received
+func (r *RotatingFile) watchSignals() {
+	for {
+		select {
+		case <-r.signalCh:
+			if err := r.Reopen(); err != nil && !errors.Is(err, ErrClosed) {
+				reportError("reopen", err)
+			}
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// shouldRotate reports whether the file must be rotated before writing n bytes
+// This method assumes the mutex is already held by the caller
+func (r *RotatingFile) shouldRotate(n int64) bool {
+	// Never rotate an empty file, so that an oversized write still goes somewhere. The
+	// interval of an empty file starts with its first write rather than when it was opened.
+	if r.size == 0 {
+		r.scheduleRotation()
+		return false
+	}
+	if r.maxSize > 0 && r.size+n > r.maxSize {
+		return true
+	}
+	return r.interval > 0 && !r.now().Before(r.nextRotation)
+}
+
+// rotate renames the current file to a backup and opens a new one
+// This method assumes the mutex is already held by the caller
+func (r *RotatingFile) rotate() error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(r.filename); err == nil {
+		backup := r.backupName(r.now())
+		if err := os.Rename(r.filename, backup); err != nil {
+			// Keep writing to the current file rather than losing logs
+			if openErr := r.openFile(); openErr != nil {
+				return errors.Join(err, openErr)
+			}
+			return fmt.Errorf("sink: rotate %s: %w", r.filename, err)
+		}
+		r.millGroup.Add(1)
+		go r.mill()
+	}
+
+	return r.openFile()
+}
+
+// openFile opens the file for appending and records its size
+// This method assumes the mutex is already held by the caller
+func (r *RotatingFile) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(r.filename), 0755); err != nil {
+		return fmt.Errorf("sink: create directory for %s: %w", r.filename, err)
+	}
+	file, err := os.OpenFile(r.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, r.fileMode)
+	if err != nil {
+		return fmt.Errorf("sink: open %s: %w", r.filename, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("sink: stat %s: %w", r.filename, err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.scheduleRotation()
+	return nil
+}
+
+// scheduleRotation sets the time of the next interval rotation to the end of the current interval
+// This method assumes the mutex is already held by the caller
+func (r *RotatingFile) scheduleRotation() {
+	if r.interval > 0 {
+		r.nextRotation = r.now().Truncate(r.interval).Add(r.interval)
+	}
+}
+
+// closeFile closes the current file
+// This method assumes the mutex is already held by the caller
+func (r *RotatingFile) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	r.size = 0
+	return err
+}
+
+// backupName returns an unused backup file name for the given rotation time
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	for {
+		name := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+		if !fileExists(name) && !fileExists(name+compressSuffix) {
+			return name
+		}
+		// Rotated twice within a millisecond; move to the next free timestamp
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// nameParts splits the file name into directory, backup prefix and extension
+func (r *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(r.filename)
+	base := filepath.Base(r.filename)
+	ext = filepath.Ext(base)
+	prefix = strings.TrimSuffix(base, ext) + "-"
+	return dir, prefix, ext
+}
+
+// mill removes backups beyond the limit and compresses the remaining ones
+// It handles all backups rather than only the newest one, so that runs started by
+// earlier rotations cannot race with it.
+func (r *RotatingFile) mill() {
+	defer r.millGroup.Done()
+
+	r.millMutex.Lock()
+	defer r.millMutex.Unlock()
+
+	if r.maxBackups > 0 {
+		if err := r.removeOldBackups(); err != nil {
+			reportError("remove_backups", err)
+		}
+	}
+	if r.compress {
+		backups, err := r.backups()
+		if err != nil {
+			reportError("compress", err)
+			return
+		}
+		for _, backup := range backups {
+			if strings.HasSuffix(backup, compressSuffix) {
+				continue
+			}
+			if err := compressFile(backup); err != nil {
+				reportError("compress", err)
+			}
+		}
+	}
+}
+
+// removeOldBackups removes the oldest backups so that at most maxBackups remain
+func (r *RotatingFile) removeOldBackups() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+	if len(backups) <= r.maxBackups {
+		return nil
+	}
+
+	var errs []error
+	for _, backup := range backups[:len(backups)-r.maxBackups] {
+		if err := os.Remove(backup); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// backups returns the paths of existing backups, oldest first
+func (r *RotatingFile) backups() ([]string, error) {
+	dir, prefix, ext := r.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type backup struct {
+		path      string
+		timestamp string
+	}
+	var found []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, compressSuffix), ext)
+		stamp = strings.TrimPrefix(stamp, prefix)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		found = append(found, backup{path: filepath.Join(dir, name), timestamp: stamp})
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].timestamp < found[j].timestamp })
+	paths := make([]string, len(found))
+	for i, b := range found {
+		paths[i] = b.path
+	}
+	return paths, nil
+}
+
+// compressFile gzips the file to "<path>.gz" and removes the original
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = dst.Close()
+			_ = os.Remove(path + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// fileExists reports whether a file exists at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// reportError passes errors from background work to the logger's global error handler
+func reportError(operation string, err error) {
+	if handler := logger.GetGlobalErrorHandler(); handler != nil {
+		handler.HandleError("sink_"+operation, logger.NewLoggerError(operation, err))
+	}
+}
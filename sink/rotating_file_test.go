@@ -0,0 +1,295 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listDir returns the sorted file names in dir
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readFile returns the content of the file, decompressing .gz files
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if strings.HasSuffix(path, compressSuffix) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Failed to open gzip %s: %v", path, err)
+		}
+		data, err = io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("Failed to decompress %s: %v", path, err)
+		}
+	}
+	return string(data)
+}
+
+func TestRotatingFile_RotatesBySizeWithoutSplitting(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	file, err := NewRotatingFile(filename, WithMaxSize(20))
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	defer file.Close()
+
+	documents := []string{
+		`{"n":1,"pad":"aa"}` + "\n",                    // 19 bytes
+		`{"n":2}` + "\n",                               // Would exceed 20 bytes -> rotate
+		`{"n":3,"pad":"larger than the limit"}` + "\n", // Oversized, gets its own file
+	}
+	for _, doc := range documents {
+		if _, err := file.Write([]byte(doc)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	file.Close()
+
+	names := listDir(t, dir)
+	if len(names) != 3 {
+		t.Fatalf("Expected current file and 2 backups, got %v", names)
+	}
+
+	var contents []string
+	for _, name := range names {
+		contents = append(contents, readFile(t, filepath.Join(dir, name)))
+	}
+	// Backups sort by rotation time and the current file sorts last ("app-..." < "app.log")
+	for i, doc := range documents {
+		if contents[i] != doc {
+			t.Errorf("Expected file %s to contain exactly %q, got %q", names[i], doc, contents[i])
+		}
+	}
+}
+
+func TestRotatingFile_RotatesByInterval(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 2, 10, 30, 0, 0, time.UTC)
+
+	file, err := NewRotatingFile(filepath.Join(dir, "app.log"), WithRotationInterval(time.Hour),
+		func(r *RotatingFile) { r.now = func() time.Time { return now } })
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	defer file.Close()
+
+	file.Write([]byte("first\n"))
+	now = now.Add(20 * time.Minute) // 10:50, same hour
+	file.Write([]byte("second\n"))
+	now = now.Add(15 * time.Minute) // 11:05, next hour
+	file.Write([]byte("third\n"))
+	file.Close()
+
+	names := listDir(t, dir)
+	expected := []string{"app-2024-01-02T11-05-00.000.log", "app.log"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+	if got := readFile(t, filepath.Join(dir, names[0])); got != "first\nsecond\n" {
+		t.Errorf("Unexpected backup content %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "app.log")); got != "third\n" {
+		t.Errorf("Unexpected current content %q", got)
+	}
+}
+
+func TestRotatingFile_IntervalStartsWithFirstWrite(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 2, 10, 50, 0, 0, time.UTC)
+
+	file, err := NewRotatingFile(filepath.Join(dir, "app.log"), WithRotationInterval(time.Hour),
+		func(r *RotatingFile) { r.now = func() time.Time { return now } })
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	defer file.Close()
+
+	now = now.Add(15 * time.Minute) // 11:05, first write after the boundary
+	file.Write([]byte("first\n"))
+	now = now.Add(5 * time.Minute) // 11:10, same hour as the first write
+	file.Write([]byte("second\n"))
+	now = now.Add(55 * time.Minute) // 12:05, next hour
+	file.Write([]byte("third\n"))
+	file.Close()
+
+	names := listDir(t, dir)
+	expected := []string{"app-2024-01-02T12-05-00.000.log", "app.log"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+	if got := readFile(t, filepath.Join(dir, names[0])); got != "first\nsecond\n" {
+		t.Errorf("Unexpected backup content %q", got)
+	}
+}
+
+func TestRotatingFile_MaxBackupsAndCompression(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	file, err := NewRotatingFile(filepath.Join(dir, "app.log"), WithMaxBackups(2), WithCompression(true),
+		func(r *RotatingFile) { r.now = func() time.Time { return now } })
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		file.Write([]byte{byte('a' + i), '\n'})
+		now = now.Add(time.Second)
+		if err := file.Rotate(); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+	}
+	file.Close()
+
+	names := listDir(t, dir)
+	expected := []string{
+		"app-2024-01-02T00-00-03.000.log.gz",
+		"app-2024-01-02T00-00-04.000.log.gz",
+		"app.log",
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+	if got := readFile(t, filepath.Join(dir, names[1])); got != "d\n" {
+		t.Errorf("Unexpected content of newest backup %q", got)
+	}
+}
+
+func TestRotatingFile_MillHandlesEarlierBackups(t *testing.T) {
+	reported := captureErrors(t)
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	// Backups left uncompressed by an earlier run
+	for _, name := range []string{"app-2024-01-01T00-00-00.000.log", "app-2024-01-01T00-00-01.000.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to create backup: %v", err)
+		}
+	}
+
+	file, err := NewRotatingFile(filepath.Join(dir, "app.log"), WithMaxBackups(2), WithCompression(true),
+		func(r *RotatingFile) { r.now = func() time.Time { return now } })
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	file.Write([]byte("a\n"))
+	if err := file.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	file.Close()
+
+	names := listDir(t, dir)
+	expected := []string{
+		"app-2024-01-01T00-00-01.000.log.gz",
+		"app-2024-01-02T00-00-00.000.log.gz",
+		"app.log",
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+	if got := reported(); len(got) != 0 {
+		t.Errorf("Expected no errors, got %v", got)
+	}
+}
+
+func TestRotatingFile_RapidRotationsDoNotRace(t *testing.T) {
+	reported := captureErrors(t)
+	dir := t.TempDir()
+
+	file, err := NewRotatingFile(filepath.Join(dir, "app.log"), WithMaxBackups(1), WithCompression(true))
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		file.Write([]byte("line\n"))
+		if err := file.Rotate(); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+	}
+	file.Close()
+
+	if names := listDir(t, dir); len(names) != 2 || !strings.HasSuffix(names[0], compressSuffix) {
+		t.Errorf("Expected one compressed backup and the current file, got %v", names)
+	}
+	if got := reported(); len(got) != 0 {
+		t.Errorf("Expected no compression or cleanup errors, got %v", got)
+	}
+}
+
+func TestRotatingFile_ConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	file, err := NewRotatingFile(filepath.Join(dir, "app.log"), WithMaxSize(256))
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+
+	line := strings.Repeat("x", 31) + "\n"
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				file.Write([]byte(line))
+			}
+		}()
+	}
+	wg.Wait()
+	file.Close()
+
+	total := 0
+	for _, name := range listDir(t, dir) {
+		content := readFile(t, filepath.Join(dir, name))
+		if len(content) > 256 {
+			t.Errorf("Expected %s to respect the size limit, got %d bytes", name, len(content))
+		}
+		for _, l := range strings.SplitAfter(content, "\n") {
+			if l != "" && l != line {
+				t.Errorf("Found a corrupted line in %s: %q", name, l)
+			}
+		}
+		total += strings.Count(content, "\n")
+	}
+	if total != 400 {
+		t.Errorf("Expected 400 lines across all files, got %d", total)
+	}
+}
+
+func TestRotatingFile_WriteAfterClose(t *testing.T) {
+	file, err := NewRotatingFile(filepath.Join(t.TempDir(), "logs", "app.log"))
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	file.Close()
+
+	if _, err := file.Write([]byte("late\n")); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Errorf("Expected second Close to succeed, got %v", err)
+	}
+}
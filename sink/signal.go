@@ -0,0 +1,13 @@
+//go:build !js
+
+package sink
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultReopenSignals returns the signals used by WithReopenOnSignal without arguments
+func defaultReopenSignals() []os.Signal {
+	return []os.Signal{syscall.SIGHUP}
+}
@@ -0,0 +1,8 @@
+package sink
+
+import "os"
+
+// defaultReopenSignals returns no signals, as js/wasm does not support SIGHUP
+func defaultReopenSignals() []os.Signal {
+	return nil
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package sink
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile_ReopenOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	file, err := NewRotatingFile(filename, WithReopenOnSignal(syscall.SIGHUP))
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	defer file.Close()
+
+	file.Write([]byte("before\n"))
+
+	// Simulate logrotate moving the file away
+	moved := filepath.Join(dir, "app.log.1")
+	if err := os.Rename(filename, moved); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Kill failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !fileExists(filename) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the file to be reopened after SIGHUP")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	file.Write([]byte("after\n"))
+	if got := readFile(t, moved); got != "before\n" {
+		t.Errorf("Unexpected content of moved file %q", got)
+	}
+	if got := readFile(t, filename); got != "after\n" {
+		t.Errorf("Unexpected content of reopened file %q", got)
+	}
+}